@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+// appliedSchema contains objects, created from schema dump before check queries
+type appliedSchema struct {
+	created []internal.SchemaObject
+	failed  []internal.SchemaObject
+}
+
+func applySchema(ctx context.Context, checker internal.QueryChecker, schema *internal.PgSchema, withViews bool) *appliedSchema {
+	objects := schema.Objects(internal.ObjectTypeTable)
+	if withViews {
+		objects = append(objects, schema.Objects(internal.ObjectTypeView)...)
+	}
+
+	log.Printf("Creating schema objects: %v", len(objects))
+	res := &appliedSchema{}
+	for _, object := range objects {
+		if object.Type == internal.ObjectTypeView {
+			// view queries refer to tables by original names
+			object.SQL = fixSchemaNames(object.SQL)
+		}
+		if err := checker.ExecQuery(ctx, object.SQL); err != nil {
+			log.Printf("Failed to create %v %v.%v: %v", object.Type, object.Schema, object.Name, err)
+			res.failed = append(res.failed, object)
+			continue
+		}
+		res.created = append(res.created, object)
+	}
+
+	log.Printf("Schema objects created: %v, failed: %v", len(res.created), len(res.failed))
+	for _, object := range res.failed {
+		log.Printf("Failed to create: %v %v.%v", object.Type, object.Schema, object.Name)
+	}
+	return res
+}
+
+// drop remove created objects in reverse order, for drop views before tables
+func (s *appliedSchema) drop(ctx context.Context, checker internal.QueryChecker) {
+	log.Printf("Drop created schema objects: %v", len(s.created))
+	for i := len(s.created) - 1; i >= 0; i-- {
+		object := s.created[i]
+		dropQuery := fmt.Sprintf("DROP %v %v", object.Type, object.ConvertedName)
+		if err := checker.ExecQuery(ctx, dropQuery); err != nil {
+			log.Printf("Failed to drop %v %v: %v", object.Type, object.ConvertedName, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+func TestApplySchemaViews(t *testing.T) {
+	schema := internal.NewPgSchema()
+	schema.Read(strings.NewReader(`CREATE TABLE public.hosts (
+    hostname text
+)
+DISTRIBUTED RANDOMLY
+;
+CREATE VIEW public.host_names AS
+ SELECT hosts.hostname FROM public.hosts;
+`))
+	views := schema.Objects(internal.ObjectTypeView)
+	require.Len(t, views, 1)
+
+	// server doesn't know original table names
+	checker := testQueryChecker{views[0].SQL: errors.New("table not found")}
+	created := applySchema(context.Background(), checker, schema, true)
+	require.Empty(t, created.failed)
+	require.Len(t, created.created, 2)
+	require.Equal(t, "CREATE VIEW public___host_names AS\n SELECT hosts.hostname FROM public___hosts;", fixSchemaNames(views[0].SQL))
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"slices"
 	"strconv"
 	"testing"
 
@@ -38,6 +39,50 @@ WHERE`,
 			from:   `SELECT s.t.f FROM s.t`,
 			result: `SELECT s___t.f FROM s___t`,
 		},
+		{
+			from:   "SELECT 'FROM a.b', $$x.y.z$$ /* FROM c.d */ FROM a.b -- JOIN e.f.g",
+			result: "SELECT 'FROM a.b', $$x.y.z$$ /* FROM c.d */ FROM a___b -- JOIN e.f.g",
+		},
+		{
+			from: `SELECT *
+FROM
+  "Sch".tbl t JOIN sch."T" ON t.a = "T".a`,
+			result: `SELECT *
+FROM
+  "Sch___tbl" t JOIN "sch___T" ON t.a = "T".a`,
+		},
+		{
+			from:   `SELECT s.t.*, "s" . "t".f FROM ONLY s.t`,
+			result: `SELECT s___t.*, s___t.f FROM ONLY s___t`,
+		},
+		{
+			from:   `REVOKE ALL PRIVILEGES ON s.t FROM public`,
+			result: `REVOKE ALL PRIVILEGES ON s___t FROM public`,
+		},
+		{
+			from:   `SELECT EXTRACT(YEAR FROM t.created_at), substring(t.name FROM 1 FOR 2) FROM s.t`,
+			result: `SELECT EXTRACT(YEAR FROM t.created_at), substring(t.name FROM 1 FOR 2) FROM s___t`,
+		},
+		{
+			from:   `SELECT * FROM s.a a, (SELECT * FROM s.c) c, s.b AS b WHERE a.x IS DISTINCT FROM b.y`,
+			result: `SELECT * FROM s___a a, (SELECT * FROM s___c) c, s___b AS b WHERE a.x IS DISTINCT FROM b.y`,
+		},
+		{
+			from:   `SELECT a.x, b.y FROM s.a a JOIN s.b b ON a.x = b.y, s.c ORDER BY a.x, b.y`,
+			result: `SELECT a.x, b.y FROM s___a a JOIN s___b b ON a.x = b.y, s___c ORDER BY a.x, b.y`,
+		},
+		{
+			from:   `UPDATE s.a SET x = 1, y = b.y FROM s.b, s.c WHERE f(a.x, b.y)`,
+			result: `UPDATE s___a SET x = 1, y = b.y FROM s___b, s___c WHERE f(a.x, b.y)`,
+		},
+		{
+			from:   `CREATE UNIQUE INDEX i ON s.t (a, b)`,
+			result: `CREATE UNIQUE INDEX i ON s___t (a, b)`,
+		},
+		{
+			from:   `TRUNCATE s.a, s.b`,
+			result: `TRUNCATE s___a, s___b`,
+		},
 		{
 			// no changes
 			from: `select a.attname, a.atttypid, t.typname
@@ -114,8 +159,33 @@ func TestCutGreenplumSpecific(t *testing.T) {
 	}
 	for _, test := range table {
 		t.Run(test.name, func(t *testing.T) {
-			res := cutUnsupportedConstructions(test.from)
+			res, _ := rewritesOnly(t, "create table as (select)", "create table as select", "distributed by", "distributed policy").Apply(test.from)
 			require.Equal(t, test.to, res)
 		})
 	}
 }
+
+// rewritesOnly return default rewrites with given names only
+func rewritesOnly(t *testing.T, names ...string) *QueryRewrites {
+	res := &QueryRewrites{}
+	for _, rewrite := range mustLoadDefaultRewrites().Rewrites {
+		if slices.Contains(names, rewrite.Name) {
+			res.Rewrites = append(res.Rewrites, rewrite)
+		}
+	}
+	require.Len(t, res.Rewrites, len(names))
+	return res
+}
+
+func TestCheckQueryCountDistinct(t *testing.T) {
+	var stats QueryStats
+	cache := newVerdictCache()
+	checker := testQueryChecker{}
+
+	checkQuery(&stats, Rules{}, checker, nil, cache, "SELECT * FROM t WHERE a = 1", 5)
+	checkQuery(&stats, Rules{}, checker, nil, cache, "SELECT * FROM t WHERE a = 2", 3)
+
+	require.Equal(t, 8, stats.GetTotalCount())
+	require.Equal(t, 1, stats.GetDistinctCount(), "fingerprint counted once independent of calls")
+	require.Equal(t, 1, stats.GetDistinctOkCount())
+}
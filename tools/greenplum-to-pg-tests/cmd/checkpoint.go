@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// inputProgress track checked queries of the query log for resume interrupted run.
+// Queries checked in parallel, so it store offset before first not checked query and checked queries after it.
+// Position is offset in the query log file after the query before offset, resume seek the log to it when possible.
+type inputProgress struct {
+	m             sync.Mutex
+	offset        int
+	position      int64
+	doneAfterOffs map[int]int64 // query index to position after the query, -1 if unknown
+}
+
+// checkpointFile saved in the stat file, so stats and position of the query log always consistent
+type checkpointFile struct {
+	QueryLog        string `yaml:"query_log"`
+	Offset          int    `yaml:"offset"`
+	Position        int64  `yaml:"position,omitempty"`
+	DoneAfterOffset []int  `yaml:"done_after_offset,omitempty"`
+}
+
+func newInputProgress() *inputProgress {
+	return &inputProgress{
+		doneAfterOffs: make(map[int]int64),
+	}
+}
+
+func newInputProgressFromCheckpoint(checkpoint checkpointFile) (*inputProgress, error) {
+	if checkpoint.QueryLog != checkPgQueriesConfig.sessionsLog {
+		return nil, fmt.Errorf("checkpoint created for other query log: %q", checkpoint.QueryLog)
+	}
+
+	res := newInputProgress()
+	res.offset = checkpoint.Offset
+	res.position = checkpoint.Position
+	for _, index := range checkpoint.DoneAfterOffset {
+		// position set when the query read again after resume
+		res.doneAfterOffs[index] = -1
+	}
+	return res, nil
+}
+
+func (p *inputProgress) IsDone(index int) bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	_, done := p.doneAfterOffs[index]
+	return index < p.offset || done
+}
+
+// Done mark query checked, end is position in the query log after the query
+func (p *inputProgress) Done(index int, end int64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if index < p.offset {
+		return
+	}
+	p.doneAfterOffs[index] = end
+	for {
+		end, ok := p.doneAfterOffs[p.offset]
+		if !ok || end < 0 {
+			break
+		}
+		delete(p.doneAfterOffs, p.offset)
+		p.offset++
+		p.position = end
+	}
+}
+
+func (p *inputProgress) Checkpoint() checkpointFile {
+	p.m.Lock()
+	checkpoint := checkpointFile{
+		QueryLog: checkPgQueriesConfig.sessionsLog,
+		Offset:   p.offset,
+		Position: p.position,
+	}
+	for index := range p.doneAfterOffs {
+		checkpoint.DoneAfterOffset = append(checkpoint.DoneAfterOffset, index)
+	}
+	p.m.Unlock()
+
+	slices.Sort(checkpoint.DoneAfterOffset)
+	return checkpoint
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputProgress(t *testing.T) {
+	progress := newInputProgress()
+	progress.Done(1, 20)
+	progress.Done(3, 40)
+	require.False(t, progress.IsDone(0))
+	require.True(t, progress.IsDone(1))
+
+	progress.Done(0, 10)
+	require.Equal(t, 2, progress.offset)
+	require.Equal(t, int64(20), progress.position)
+
+	var stats QueryStats
+	stats.CountASOK("SELECT 1", 1)
+	checkpoint := progress.Checkpoint()
+	path := filepath.Join(t.TempDir(), "stat.yaml")
+	require.NoError(t, stats.SaveToFileWithCheckpoint(path, &checkpoint))
+
+	statFile, err := readStatFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, statFile.TotalCount)
+	require.NotNil(t, statFile.Checkpoint)
+
+	loaded, err := newInputProgressFromCheckpoint(*statFile.Checkpoint)
+	require.NoError(t, err)
+	require.Equal(t, 2, loaded.offset)
+	require.Equal(t, int64(20), loaded.position)
+	require.True(t, loaded.IsDone(0))
+	require.False(t, loaded.IsDone(2))
+	require.True(t, loaded.IsDone(3))
+	require.False(t, loaded.IsDone(4))
+
+	// position after query 3 unknown until it read again
+	loaded.Done(2, 30)
+	require.Equal(t, 3, loaded.offset)
+	require.Equal(t, int64(30), loaded.position)
+	loaded.Done(3, 40)
+	require.Equal(t, 4, loaded.offset)
+	require.Equal(t, int64(40), loaded.position)
+}
@@ -1,5 +1,11 @@
 package cmd
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
 func must0(err error) {
 	if err != nil {
 		panic(err)
@@ -10,3 +16,33 @@ func must[R any](res R, err error) R {
 	must0(err)
 	return res
 }
+
+// writeFileAtomic write content to temp file near the path and rename it to the path,
+// so readers and interrupted runs never see partially written file
+func writeFileAtomic(path string, content []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %q: %w", path, err)
+	}
+	tempPath := f.Name()
+	defer func() { _ = os.Remove(tempPath) }()
+
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temp file for %q: %w", path, err)
+	}
+
+	if err = os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("failed to rename temp file to %q: %w", path, err)
+	}
+	return nil
+}
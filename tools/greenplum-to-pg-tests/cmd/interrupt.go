@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptContext return context, cancelled by first SIGINT or SIGTERM. The context stop reading of input only,
+// started checks finish and results written as usual. Second signal exit immediately.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %v, stop reading queries, wait inflight checks and write results. Repeat for force exit.", sig)
+		cancel()
+
+		sig = <-signals
+		log.Printf("Received %v again, force exit without write results", sig)
+		os.Exit(130)
+	}()
+	return ctx
+}
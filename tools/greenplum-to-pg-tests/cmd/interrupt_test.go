@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stat.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("old content"), 0o644))
+
+	require.NoError(t, writeFileAtomic(path, []byte("new")))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "new", string(content))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temp file removed")
+
+	require.Error(t, writeFileAtomic(filepath.Join(dir, "not-exists", "stat.yaml"), []byte("new")))
+}
+
+func TestExtractQueriesStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sessions := make(chan internal.Session, 1)
+	var transaction internal.Transaction
+	transaction.LogSuccess = true
+	for range 10 {
+		transaction.Queries = append(transaction.Queries, internal.Query{Text: "SELECT 1"})
+	}
+	sessions <- internal.Session{Transactions: []internal.Transaction{transaction}}
+
+	queries := extractQueries(ctx, sessions, 10)
+	<-queries
+	cancel()
+
+	count := 0
+	for range queries {
+		count++
+	}
+	require.LessOrEqual(t, count, 1, "reading stopped, queries channel closed")
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"cmp"
 	"errors"
 	"fmt"
 	"log"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"regexp"
 	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
@@ -17,16 +19,21 @@ import (
 
 type Rules struct {
 	TotalStat struct {
-		TotalCount int     `yaml:"total_checked_queries,omitempty"`
-		TotalOk    int     `yaml:"total_ok,omitempty"`
-		OkPercent  float64 `yaml:"ok_percent,omitempty"`
+		TotalCount    int     `yaml:"total_checked_queries,omitempty"`
+		TotalOk       int     `yaml:"total_ok,omitempty"`
+		OkPercent     float64 `yaml:"ok_percent,omitempty"`
+		DistinctCount int     `yaml:"distinct_queries,omitempty"`
+		DistinctOk    int     `yaml:"distinct_ok,omitempty"`
 	} `yaml:"stat"`
 
 	Issues []PgIssueRules
+
+	byPriority []PgIssueRules // match order of Issues, Issues keep file order for write back
 }
 
 func (r *Rules) LoadFromFile(path string) error {
 	r.Issues = nil
+	r.byPriority = nil
 
 	f, err := os.Open(path)
 	if err != nil {
@@ -57,9 +64,24 @@ func (r *Rules) LoadFromFile(path string) error {
 		}
 	}
 
+	r.byPriority = sortedByPriority(r.Issues)
 	return nil
 }
 
+func sortedByPriority(issues []PgIssueRules) []PgIssueRules {
+	res := slices.Clone(issues)
+	slices.SortStableFunc(res, comparePriority)
+	return res
+}
+
+// issuesByPriority return rules in match order, rules created without LoadFromFile sorted on every call
+func (r *Rules) issuesByPriority() []PgIssueRules {
+	if r.byPriority == nil {
+		return sortedByPriority(r.Issues)
+	}
+	return r.byPriority
+}
+
 func (r *Rules) WriteToFile(path string) error {
 	buf := &bytes.Buffer{}
 	encoder := yaml.NewEncoder(buf)
@@ -68,9 +90,9 @@ func (r *Rules) WriteToFile(path string) error {
 		return fmt.Errorf("failed to encode rules: %w", err)
 	}
 
-	err = os.WriteFile(path, buf.Bytes(), 0666)
+	err = writeFileAtomic(path, buf.Bytes())
 	if err != nil {
-		return fmt.Errorf("failed write file to update rules")
+		return fmt.Errorf("failed write file to update rules: %w", err)
 	}
 	return nil
 }
@@ -79,9 +101,11 @@ func (r *Rules) MatchToKnownIssues(queryText string, ydbIssues []internal.YdbIss
 	var res []PgIssueRules
 	var restYdbIssues []internal.YdbIssue
 
+	issues := r.issuesByPriority()
+
 ydbIssue:
 	for _, ydbIssue := range ydbIssues {
-		for _, item := range r.Issues {
+		for _, item := range issues {
 			if item.IsMatched(queryText, ydbIssue) {
 				res = append(res, item)
 				if item.PrintIssueToLog {
@@ -100,6 +124,8 @@ func (r *Rules) UpdateFromStats(stats *QueryStats, sortByCount bool) {
 	r.TotalStat.TotalCount = stats.GetTotalCount()
 	r.TotalStat.TotalOk = stats.GetOkCount()
 	r.TotalStat.OkPercent = math.Round(stats.GetOkPercent()*100) / 100
+	r.TotalStat.DistinctCount = stats.GetDistinctCount()
+	r.TotalStat.DistinctOk = stats.GetDistinctOkCount()
 
 	okStats := stats.GetTopKnown(math.MaxInt)
 	for _, stat := range okStats {
@@ -110,13 +136,33 @@ func (r *Rules) UpdateFromStats(stats *QueryStats, sortByCount bool) {
 		}
 	}
 
+	for issueIndex, issue := range r.Issues {
+		if buckets := stats.GetTopBuckets(issue.Name, rulesFileBucketsLimit); len(buckets) > 0 {
+			for i := range buckets {
+				buckets[i].Example = cleanStringForLiteralYaml(buckets[i].Example)
+			}
+			r.Issues[issueIndex].Buckets = buckets
+		}
+	}
+
 	if sortByCount {
-		slices.SortFunc(r.Issues, func(a, b PgIssueRules) int {
-			return b.Count - a.Count
+		slices.SortStableFunc(r.Issues, func(a, b PgIssueRules) int {
+			return cmp.Or(
+				comparePriority(a, b),
+				b.Count-a.Count,
+			)
 		})
 	}
 }
 
+// rulesFileBucketsLimit is max count of capture buckets, written to rules file for every rule
+const rulesFileBucketsLimit = 20
+
+// comparePriority order rules with greater priority first
+func comparePriority(a, b PgIssueRules) int {
+	return cmp.Compare(b.Priority, a.Priority)
+}
+
 type PgIssueRules struct {
 	Name            string           `yaml:"name"`
 	Count           int              `yaml:"count"`
@@ -126,8 +172,13 @@ type PgIssueRules struct {
 	QueryRegexp     OneOrSliceString `yaml:"query_regexp,omitempty"`
 	Example         string           `yaml:"example,omitempty"`
 	Comment         string           `yaml:"comment,omitempty"`
-	Skip            bool             `yaml:"skip,omitempty"` // skip the issue on check query step
+	Skip            bool             `yaml:"skip,omitempty"`     // skip the issue on check query step
+	Priority        int              `yaml:"priority,omitempty"` // rules with greater priority matched first, same priority in file order
 	PrintIssueToLog bool             `yaml:"print_issue_to_log,omitempty"`
+	Tests           []RuleTest       `yaml:"tests,omitempty"` // offline checks for regexps, run by rules test
+
+	// Buckets is top counts by values of named capture groups of the regexps, updated from stats
+	Buckets []CounterWithExample[string] `yaml:"buckets,omitempty"`
 
 	issuesRegexpCompiled []*regexp.Regexp
 	queryRegexpCompiled  []*regexp.Regexp
@@ -181,6 +232,54 @@ func (r *PgIssueRules) IsMatched(query string, issue internal.YdbIssue) bool {
 	return allowByQuery
 }
 
+// HasCaptures return true if regexps of the rule has named capture groups
+func (r *PgIssueRules) HasCaptures() bool {
+	for _, re := range slices.Concat(r.issuesRegexpCompiled, r.queryRegexpCompiled) {
+		if slices.ContainsFunc(re.SubexpNames(), func(name string) bool { return name != "" }) {
+			return true
+		}
+	}
+	return false
+}
+
+// CaptureBucket return values of named capture groups of first matched issue and query regexps
+// in format "name=value, name2=value2". Return empty string if the rule doesn't match or hasn't captures.
+func (r *PgIssueRules) CaptureBucket(query string, issue internal.YdbIssue) string {
+	if !r.IsMatched(query, issue) {
+		return ""
+	}
+
+	var parts []string
+	addCaptures := func(regexps []*regexp.Regexp, text string) {
+		for _, re := range regexps {
+			match := re.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					parts = append(parts, name+"="+match[i])
+				}
+			}
+			return
+		}
+	}
+	addCaptures(r.issuesRegexpCompiled, issue.Message)
+	addCaptures(r.queryRegexpCompiled, query)
+	return strings.Join(parts, ", ")
+}
+
+// RuleTest is example of issue, which should (or shouldn't) be matched by the rule
+type RuleTest struct {
+	Query string `yaml:"query,omitempty"`
+	Issue string `yaml:"issue"`
+	Match *bool  `yaml:"match,omitempty"` // true if empty
+}
+
+func (t RuleTest) ShouldMatch() bool {
+	return t.Match == nil || *t.Match
+}
+
 type OneOrSliceString []string
 
 func (s *OneOrSliceString) Strings() []string {
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+func TestRulesPriority(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+issues:
+  - name: generic
+    issue_regexp: "not supported"
+  - name: specific
+    priority: 10
+    issue_regexp: "^onConflict not supported$"
+`), 0o644))
+
+	var rules Rules
+	require.NoError(t, rules.LoadFromFile(path))
+	// file order kept for write back
+	require.Equal(t, "generic", rules.Issues[0].Name)
+
+	reason, checkResult := classifyOutcome(rules, "INSERT", checkOutcome{
+		ErrorName: "GENERIC_ERROR (400080)",
+		Issues:    []internal.YdbIssue{{Message: "onConflict not supported"}},
+	})
+	require.Equal(t, checkResultErrKnown, checkResult)
+	require.Equal(t, "specific", reason)
+
+	require.NoError(t, rules.WriteToFile(path))
+	var written Rules
+	require.NoError(t, written.LoadFromFile(path))
+	require.Equal(t, []string{"generic", "specific"}, []string{written.Issues[0].Name, written.Issues[1].Name})
+}
+
+func TestCountMatchedRules(t *testing.T) {
+	rules := Rules{Issues: []PgIssueRules{
+		{Name: "least", IssueRegexp: OneOrSliceString{"^least$"}},
+		{Name: "distinct", IssueRegexp: OneOrSliceString{"^distinct$"}, Priority: 1},
+		{Name: "skipped", IssueRegexp: OneOrSliceString{"^skipped$"}, Skip: true},
+	}}
+	for i := range rules.Issues {
+		require.NoError(t, rules.Issues[i].Init())
+	}
+
+	var stats QueryStats
+	count := func(queryText string, messages ...string) {
+		var issues []internal.YdbIssue
+		for _, message := range messages {
+			issues = append(issues, internal.YdbIssue{Message: message})
+		}
+		outcome := checkOutcome{ErrorName: "GENERIC_ERROR (400080)", Issues: issues}
+		match := matchOutcome(rules, queryText, outcome)
+		reason, checkResult := classifyMatch(outcome, match)
+		countCheckResult(&stats, reason, checkResult, queryText, 1)
+		countMatchedRules(&stats, outcome, match, queryText, 1)
+	}
+	count("q1", "least")
+	count("q2", "least", "distinct")
+	count("q3", "least", "distinct", "least")
+	count("q4", "least", "unknown")
+	count("q5", "least", "skipped")
+
+	require.Equal(t, 3, stats.MatchToRules["least"].Count)
+	require.Equal(t, 2, stats.MatchToRules["distinct"].Count)
+	require.Equal(t, 5, stats.AllMatches["least"].Count)
+	require.Equal(t, 2, stats.AllMatches["distinct"].Count)
+
+	require.Equal(t, []blockingSet{
+		{Rules: []string{"distinct", "least"}, Count: 2, Unlocked: 3, Example: "q2"},
+		{Rules: []string{"least"}, Count: 1, Unlocked: 1, Example: "q1"},
+	}, stats.getBlockingSetsNeedLock(10))
+	require.Len(t, stats.getBlockingSetsNeedLock(1), 1)
+
+	path := filepath.Join(t.TempDir(), "stat.yaml")
+	require.NoError(t, stats.SaveToFile(path))
+	var loaded QueryStats
+	require.NoError(t, loaded.LoadFromFile(path))
+	require.Equal(t, stats.getBlockingSetsNeedLock(10), loaded.getBlockingSetsNeedLock(10))
+}
+
+func TestRuleCaptureBuckets(t *testing.T) {
+	rules := Rules{Issues: []PgIssueRules{
+		{Name: "proc", IssueRegexp: OneOrSliceString{`No such proc: (?P<proc>\w+)`}, QueryRegexp: OneOrSliceString{`^(?P<verb>\w+)`}},
+		{Name: "plain", IssueRegexp: OneOrSliceString{"^plain$"}},
+	}}
+	for i := range rules.Issues {
+		require.NoError(t, rules.Issues[i].Init())
+	}
+	require.True(t, rules.Issues[0].HasCaptures())
+	require.False(t, rules.Issues[1].HasCaptures())
+
+	var stats QueryStats
+	count := func(queryText string, message string, count int) {
+		outcome := checkOutcome{ErrorName: "GENERIC_ERROR (400080)", Issues: []internal.YdbIssue{{Message: message}}}
+		match := matchOutcome(rules, queryText, outcome)
+		countMatchedRules(&stats, outcome, match, queryText, count)
+	}
+	count("SELECT f1()", "No such proc: f1", 1)
+	count("SELECT f2()", "No such proc: f2", 5)
+	count("select f1(2)", "No such proc: f1", 2)
+	count("q", "plain", 1)
+
+	require.Equal(t, []CounterWithExample[string]{
+		{ID: "proc=f2, verb=SELECT", Count: 5, Example: "SELECT f2()"},
+		{ID: "proc=f1, verb=select", Count: 2, Example: "select f1(2)"},
+	}, stats.GetTopBuckets("proc", 2))
+	require.Len(t, stats.GetTopBuckets("proc", 10), 3)
+	require.Empty(t, stats.GetTopBuckets("plain", 10))
+
+	path := filepath.Join(t.TempDir(), "stat.yaml")
+	require.NoError(t, stats.SaveToFile(path))
+	var loaded QueryStats
+	require.NoError(t, loaded.LoadFromFile(path))
+	require.Equal(t, stats.GetTopBuckets("proc", 10), loaded.GetTopBuckets("proc", 10))
+
+	rules.UpdateFromStats(&loaded, false)
+	require.Len(t, rules.Issues[0].Buckets, 3)
+	require.Equal(t, "proc=f2, verb=SELECT", rules.Issues[0].Buckets[0].ID)
+	require.Empty(t, rules.Issues[1].Buckets)
+}
+
+func TestIssuesFileRules(t *testing.T) {
+	var rules Rules
+	require.NoError(t, rules.LoadFromFile("../issues.yaml"))
+	require.Empty(t, testRules(rules))
+
+	bucket := func(ruleName, queryText, message string) string {
+		for _, rule := range rules.Issues {
+			if rule.Name == ruleName {
+				return rule.CaptureBucket(queryText, internal.YdbIssue{Message: message})
+			}
+		}
+		t.Fatalf("rule %q not found", ruleName)
+		return ""
+	}
+	require.Equal(t, `proc="MyFunc"`, bucket("Stored procedures", "SELECT 1", `No such proc: "MyFunc"`))
+	require.Equal(t, "", bucket("Stored procedures", "SELECT 1", "No such proc: "))
+	require.Equal(t, "function=s.f", bucket("Call function from own schema", "SELECT pg_catalog.now(), s.f(1)", "FuncCall: expected pg_catalog, but got: s"))
+	require.Equal(t, `function="MySchema"."Func"`, bucket("Call function from own schema", `SELECT PG_CATALOG.now(), "MySchema"."Func"()`, "FuncCall: expected pg_catalog, but got: MySchema"))
+	require.Equal(t, "function=pgx.f", bucket("Call function from own schema", "SELECT pg_temp.g(), p.a, pgx.f()", "FuncCall: expected pg_catalog, but got: pgx"))
+	require.Equal(t, "function=", bucket("Call function from own schema", "SELECT 1", "FuncCall: expected pg_catalog, but got: s"))
+}
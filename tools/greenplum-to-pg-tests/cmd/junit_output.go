@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",cdata"`
+}
+
+// junitFromStats convert stats to junit report: every rule and every unknown reason is testcase.
+// Rule with matched queries is failed, rule without matched queries is skipped.
+// Names of testcases are stable between runs for track history in CI, counts are in properties and messages.
+func junitFromStats(className string, rules Rules, stats *QueryStats) junitTestSuites {
+	suite := junitTestSuite{Name: className}
+	add := func(testCase junitTestCase) {
+		testCase.Time = "0.000"
+		testCase.Name = cleanXmlText(testCase.Name)
+		for _, message := range []*junitMessage{testCase.Failure, testCase.Skipped} {
+			if message != nil {
+				message.Message = cleanXmlText(message.Message)
+				message.Body = cleanXmlText(message.Body)
+			}
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+		switch {
+		case testCase.Failure != nil:
+			suite.Failures++
+		case testCase.Skipped != nil:
+			suite.Skipped++
+		}
+	}
+	failure := func(count int, example string) *junitMessage {
+		return &junitMessage{Message: fmt.Sprintf("Failed queries: %v", count), Body: example}
+	}
+
+	suite.Properties = []junitProperty{
+		{Name: "ok_count", Value: strconv.Itoa(stats.GetOkCount())},
+		{Name: "total_count", Value: strconv.Itoa(stats.GetTotalCount())},
+	}
+	add(junitTestCase{Name: "ok queries", ClassName: className})
+
+	known := stats.GetTopKnown(math.MaxInt)
+	knownByName := make(map[string]CounterWithExample[string], len(known))
+	for _, stat := range known {
+		knownByName[stat.ID] = stat
+	}
+	ruleNames := make(map[string]bool, len(rules.Issues))
+	for _, rule := range rules.Issues {
+		ruleNames[rule.Name] = true
+		testCase := junitTestCase{Name: rule.Name, ClassName: className + ".known"}
+		if stat, ok := knownByName[rule.Name]; ok && stat.Count > 0 {
+			testCase.Failure = failure(stat.Count, stat.Example)
+		} else {
+			testCase.Skipped = &junitMessage{Message: "No queries with the issue"}
+		}
+		add(testCase)
+	}
+	for _, stat := range known {
+		if !ruleNames[stat.ID] {
+			add(junitTestCase{Name: stat.ID, ClassName: className + ".known", Failure: failure(stat.Count, stat.Example)})
+		}
+	}
+
+	for _, stat := range stats.GetTopUnknown(math.MaxInt) {
+		add(junitTestCase{Name: stat.ID, ClassName: className + ".unknown", Failure: failure(stat.Count, stat.Example)})
+	}
+
+	stats.m.RLock()
+	mismatches := getTopCounter(stats.SemanticMismatches, math.MaxInt)
+	stats.m.RUnlock()
+	for _, stat := range mismatches {
+		add(junitTestCase{Name: stat.ID, ClassName: className + ".semantic-mismatch", Failure: failure(stat.Count, stat.Example)})
+	}
+
+	return junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+// cleanXmlText remove characters, which are invalid in XML 1.0 and can't be written even in CDATA.
+// "]]>" in CDATA is split by xml encoder.
+func cleanXmlText(s string) string {
+	isValid := func(r rune) bool {
+		return r == '\t' || r == '\n' || r == '\r' ||
+			r >= 0x20 && r <= 0xD7FF ||
+			r >= 0xE000 && r <= 0xFFFD ||
+			r >= 0x10000 && r <= utf8.MaxRune
+	}
+	if utf8.ValidString(s) && strings.IndexFunc(s, func(r rune) bool { return !isValid(r) }) < 0 {
+		return s
+	}
+
+	var res strings.Builder
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				continue
+			}
+		}
+		if isValid(r) {
+			res.WriteRune(r)
+		}
+	}
+	return res.String()
+}
+
+func writeJunit(w io.Writer, report junitTestSuites) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeJunitFile(path string, className string, rules Rules, stats *QueryStats) error {
+	buf := &bytes.Buffer{}
+	if err := writeJunit(buf, junitFromStats(className, rules, stats)); err != nil {
+		return fmt.Errorf("failed to encode junit report: %w", err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write junit file: %w", err)
+	}
+	return nil
+}
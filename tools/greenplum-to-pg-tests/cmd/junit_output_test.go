@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJunitFromStats(t *testing.T) {
+	var stats QueryStats
+	stats.CountASOK("SELECT 1", 5)
+	stats.CountAsKnown("least", "select least(1,2)", 2)
+	stats.CountAsUnknown("strange error", "SELECT x", 1)
+	stats.CountAsUnknown("binary error", "SELECT '\x00\x1b]]>\xff'", 2)
+
+	rules := Rules{Issues: []PgIssueRules{{Name: "least"}, {Name: "is distinct"}}}
+	report := junitFromStats("query-log", rules, &stats)
+	require.Equal(t, 5, report.Tests)
+	require.Equal(t, 3, report.Failures)
+	require.Equal(t, 1, report.Skipped)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeJunit(buf, report))
+
+	var parsed junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+	cases := parsed.Suites[0].TestCases
+	require.Equal(t, "ok queries", cases[0].Name, "name must be same on every run")
+	require.Nil(t, cases[0].Failure)
+	require.Equal(t, []junitProperty{{Name: "ok_count", Value: "5"}, {Name: "total_count", Value: "10"}}, parsed.Suites[0].Properties)
+
+	require.Equal(t, "least", cases[1].Name)
+	require.Equal(t, "query-log.known", cases[1].ClassName)
+	require.Equal(t, "select least(1,2)", cases[1].Failure.Body)
+
+	require.Equal(t, "is distinct", cases[2].Name)
+	require.NotNil(t, cases[2].Skipped)
+
+	require.Equal(t, "strange error", cases[4].Name)
+	require.Equal(t, "query-log.unknown", cases[4].ClassName)
+	require.Equal(t, "Failed queries: 1", cases[4].Failure.Message)
+
+	require.Equal(t, "binary error", cases[3].Name)
+	require.Equal(t, "SELECT ']]>'", cases[3].Failure.Body)
+}
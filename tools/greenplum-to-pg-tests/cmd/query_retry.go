@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ydb-platform/ydb-go-genproto/protos/Ydb"
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+)
+
+// queryRetryPolicy limit time of every query and retry queries, failed by overload or unavailable server
+type queryRetryPolicy struct {
+	timeout time.Duration // timeout of one attempt, 0 mean without timeout
+	retries int           // max retries after first attempt
+	backoff time.Duration // delay before first retry, doubled for every next retry
+}
+
+func configuredRetryPolicy() queryRetryPolicy {
+	return queryRetryPolicy{
+		timeout: checkPgQueriesConfig.queryTimeout,
+		retries: checkPgQueriesConfig.queryRetries,
+		backoff: checkPgQueriesConfig.queryRetryBackoff,
+	}
+}
+
+// do call f with deadline and retry retryable errors. Result is error of last attempt.
+func (p queryRetryPolicy) do(ctx context.Context, f func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := p.attempt(ctx, f)
+		if attempt >= p.retries || !isRetryableError(err) {
+			return err
+		}
+
+		delay := p.backoff << attempt
+		delay += rand.N(delay/2 + 1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (p queryRetryPolicy) attempt(ctx context.Context, f func(ctx context.Context) error) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	return f(ctx)
+}
+
+var retryableYdbCodes = []Ydb.StatusIds_StatusCode{
+	Ydb.StatusIds_OVERLOADED,
+	Ydb.StatusIds_UNAVAILABLE,
+	Ydb.StatusIds_BAD_SESSION,
+	Ydb.StatusIds_SESSION_BUSY,
+	Ydb.StatusIds_SESSION_EXPIRED,
+	Ydb.StatusIds_UNDETERMINED,
+}
+
+// isRetryableError detect errors of overloaded or unavailable server, the query may pass on next attempt
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if ydb.IsTransportError(err) || ydb.IsOperationError(err, retryableYdbCodes...) {
+		return true
+	}
+
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) {
+		code := string(pgErr.Code)
+		// connection exception, insufficient resources, operator intervention except query canceled
+		return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "53") ||
+			strings.HasPrefix(code, "57P")
+	}
+	return false
+}
+
+// isTransientError detect errors, which doesn't say anything about compatibility of the query:
+// retryable errors and timeouts
+func isTransientError(err error) bool {
+	if isRetryableError(err) || errors.Is(err, context.DeadlineExceeded) || ydb.IsOperationError(err, Ydb.StatusIds_TIMEOUT) {
+		return true
+	}
+
+	var pgErr *pq.Error
+	return errors.As(err, &pgErr) && pgErr.Code.Name() == "query_canceled"
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRetryPolicy(t *testing.T) {
+	overloaded := &pq.Error{Code: "53300", Message: "too many connections"}
+	syntax := &pq.Error{Code: "42601", Message: "syntax error"}
+
+	table := []struct {
+		name     string
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{name: "Ok", errs: []error{nil}, attempts: 1},
+		{name: "NotRetryable", errs: []error{syntax}, attempts: 1, err: syntax},
+		{name: "RetryThenOk", errs: []error{overloaded, overloaded, nil}, attempts: 3},
+		{name: "RetriesExhausted", errs: []error{overloaded, overloaded, overloaded, overloaded}, attempts: 3, err: overloaded},
+	}
+
+	policy := queryRetryPolicy{retries: 2, backoff: time.Millisecond}
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := policy.do(context.Background(), func(ctx context.Context) error {
+				attempts++
+				return test.errs[attempts-1]
+			})
+			require.Equal(t, test.attempts, attempts)
+			require.Equal(t, test.err, err)
+		})
+	}
+}
+
+func TestQueryRetryPolicyTimeout(t *testing.T) {
+	policy := queryRetryPolicy{timeout: time.Millisecond, retries: 2, backoff: time.Millisecond}
+	attempts := 0
+	err := policy.do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.Equal(t, 1, attempts, "timeout doesn't retried")
+	require.True(t, isTransientError(err))
+
+	outcome := newCheckOutcome(fmt.Errorf("check: %w", err))
+	require.True(t, outcome.Transient)
+	reason, checkResult := classifyOutcome(Rules{}, "SELECT 1", outcome)
+	require.Equal(t, checkResultTransient, checkResult)
+	require.Contains(t, reason, "deadline exceeded")
+}
+
+func TestCountTransient(t *testing.T) {
+	var stats QueryStats
+	countCheckResult(&stats, "", checkResultOK, "q1", 1)
+	countCheckResult(&stats, "OVERLOADED (400060)", checkResultTransient, "q2", 2)
+
+	require.Equal(t, 1, stats.GetTotalCount())
+	require.Equal(t, 100.0, stats.GetOkPercent())
+	require.Equal(t, 2, stats.transientCount)
+
+	require.False(t, isTransientError(errors.New("other")))
+	require.False(t, isTransientError(nil))
+	require.True(t, isTransientError(&pq.Error{Code: "57014"}))
+}
+
+func TestAllRetryableError(t *testing.T) {
+	overloaded := &pq.Error{Code: "53300", Message: "too many connections"}
+	syntax := &pq.Error{Code: "42601", Message: "syntax error"}
+
+	table := []struct {
+		name string
+		errs []error
+		err  error
+	}{
+		{name: "Empty"},
+		{name: "AllRetryable", errs: []error{overloaded, overloaded}, err: overloaded},
+		{name: "SomeCommitted", errs: []error{nil, overloaded}},
+		{name: "NotRetryable", errs: []error{overloaded, syntax}},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.err, allRetryableError(test.errs))
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+//go:embed rewrites.yaml
+var defaultRewritesYaml []byte
+
+// queryRewrites applied to every query before check, may be replaced by --rewrites-file
+var queryRewrites = mustLoadDefaultRewrites()
+
+// rewriteBuiltins is rewrites, which too complex for regexp or tokens
+var rewriteBuiltins = map[string]func(string) string{
+	"schema_names": fixSchemaNames,
+}
+
+type QueryRewrites struct {
+	Rewrites []QueryRewrite `yaml:"rewrites"`
+}
+
+type QueryRewrite struct {
+	Name    string        `yaml:"name"`
+	Enabled *bool         `yaml:"enabled,omitempty"` // true if empty
+	Comment string        `yaml:"comment,omitempty"`
+	Regexp  string        `yaml:"regexp,omitempty"`
+	Tokens  string        `yaml:"tokens,omitempty"`
+	Replace string        `yaml:"replace,omitempty"`
+	Builtin string        `yaml:"builtin,omitempty"`
+	Tests   []RewriteTest `yaml:"tests,omitempty"`
+
+	regexpCompiled *regexp.Regexp
+	tokensCompiled []internal.Token
+	builtinFunc    func(string) string
+}
+
+type RewriteTest struct {
+	Query  string `yaml:"query"`
+	Result string `yaml:"result"`
+}
+
+func mustLoadDefaultRewrites() *QueryRewrites {
+	var res QueryRewrites
+	must0(res.Load(defaultRewritesYaml))
+	return &res
+}
+
+func (r *QueryRewrites) LoadFromFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read rewrites file %q: %w", path, err)
+	}
+	if err = r.Load(content); err != nil {
+		return fmt.Errorf("failed to load rewrites file %q: %w", path, err)
+	}
+	return nil
+}
+
+func (r *QueryRewrites) Load(content []byte) error {
+	r.Rewrites = nil
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(r); err != nil {
+		return fmt.Errorf("failed to parse rewrites: %w", err)
+	}
+
+	knownNames := map[string]bool{}
+	for i := range r.Rewrites {
+		rewrite := &r.Rewrites[i]
+		if knownNames[rewrite.Name] {
+			return fmt.Errorf("name of the rewrite duplicated: %q", rewrite.Name)
+		}
+		knownNames[rewrite.Name] = true
+
+		if err := rewrite.Init(); err != nil {
+			return fmt.Errorf("failed to init rewrite %q: %w", rewrite.Name, err)
+		}
+	}
+	return nil
+}
+
+// Apply run enabled rewrites in order and return result text and names of rewrites, which changed the text
+func (r *QueryRewrites) Apply(queryText string) (string, []string) {
+	var fired []string
+	for i := range r.Rewrites {
+		rewrite := &r.Rewrites[i]
+		if !rewrite.IsEnabled() {
+			continue
+		}
+		res := rewrite.Apply(queryText)
+		if res != queryText {
+			fired = append(fired, rewrite.Name)
+			queryText = res
+		}
+	}
+	return queryText, fired
+}
+
+// Test run tests of every rewrite and return descriptions of failures
+func (r *QueryRewrites) Test() []string {
+	var failures []string
+	for _, rewrite := range r.Rewrites {
+		for i, test := range rewrite.Tests {
+			if res := rewrite.Apply(test.Query); res != test.Result {
+				failures = append(failures, fmt.Sprintf("%v: test %v: got %q instead of %q", rewrite.Name, i, res, test.Result))
+			}
+		}
+	}
+	return failures
+}
+
+func (r *QueryRewrite) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+func (r *QueryRewrite) Init() error {
+	if r.Name == "" {
+		return errors.New("empty name")
+	}
+
+	kinds := 0
+	if r.Regexp != "" {
+		kinds++
+		var err error
+		if r.regexpCompiled, err = regexp.Compile(r.Regexp); err != nil {
+			return fmt.Errorf("failed to compile regexp %q: %w", r.Regexp, err)
+		}
+	}
+	if r.Tokens != "" {
+		kinds++
+		for _, token := range internal.LexSQL(r.Tokens) {
+			if token.IsMeaningful() {
+				r.tokensCompiled = append(r.tokensCompiled, token)
+			}
+		}
+	}
+	if r.Builtin != "" {
+		kinds++
+		if r.builtinFunc = rewriteBuiltins[r.Builtin]; r.builtinFunc == nil {
+			return fmt.Errorf("unknown builtin rewrite %q", r.Builtin)
+		}
+		if r.Replace != "" {
+			return errors.New("replace can't be used with builtin rewrite")
+		}
+	}
+	if kinds != 1 {
+		return errors.New("rewrite must have exactly one of regexp, tokens or builtin")
+	}
+	return nil
+}
+
+func (r *QueryRewrite) Apply(queryText string) string {
+	switch {
+	case r.regexpCompiled != nil:
+		return r.regexpCompiled.ReplaceAllString(queryText, r.Replace)
+	case r.tokensCompiled != nil:
+		return r.replaceTokens(queryText)
+	default:
+		return r.builtinFunc(queryText)
+	}
+}
+
+// replaceTokens replace every sequence of meaningful tokens, which match the pattern.
+// Whitespaces and comments inside the sequence replaced too.
+func (r *QueryRewrite) replaceTokens(queryText string) string {
+	tokens := internal.LexSQL(queryText)
+	buf := &strings.Builder{}
+	for i := 0; i < len(tokens); {
+		if end := matchTokens(tokens, i, r.tokensCompiled); end > 0 {
+			buf.WriteString(r.Replace)
+			i = end
+			continue
+		}
+		buf.WriteString(tokens[i].Text)
+		i++
+	}
+	return buf.String()
+}
+
+// matchTokens return index of token after matched sequence, which started from tokens[start], or 0 if the pattern doesn't match
+func matchTokens(tokens []internal.Token, start int, pattern []internal.Token) int {
+	pos := start
+	for patternIndex := 0; patternIndex < len(pattern); patternIndex++ {
+		if patternIndex > 0 {
+			for pos < len(tokens) && !tokens[pos].IsMeaningful() {
+				pos++
+			}
+		}
+		if pos >= len(tokens) || !tokens[pos].IsMeaningful() {
+			return 0
+		}
+
+		expected := pattern[patternIndex]
+		token := tokens[pos]
+		switch {
+		case expected.Text == "?" && expected.Kind == internal.TokenOperator:
+			pos++
+		case expected.Text == "(" && patternIndex+1 < len(pattern) && pattern[patternIndex+1].Text == ")":
+			end := balancedParenthesesEnd(tokens, pos)
+			if end == 0 {
+				return 0
+			}
+			pos = end
+			patternIndex++
+		case expected.Kind == internal.TokenIdentifier && token.IsKeyword(expected.Text),
+			expected.Kind != internal.TokenIdentifier && token.Text == expected.Text:
+			pos++
+		default:
+			return 0
+		}
+	}
+	return pos
+}
+
+// balancedParenthesesEnd return index of token after closing parenthesis for tokens[start] or 0 if it isn't found
+func balancedParenthesesEnd(tokens []internal.Token, start int) int {
+	if tokens[start].Text != "(" {
+		return 0
+	}
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRewrites(t *testing.T) {
+	rewrites := mustLoadDefaultRewrites()
+	require.Empty(t, rewrites.Test())
+
+	res, fired := rewrites.Apply("CREATE TABLE s.t AS SELECT 1 FROM s.t2 DISTRIBUTED RANDOMLY")
+	require.Equal(t, "SELECT 1 FROM s___t2 ", res)
+	require.Equal(t, []string{"schema names", "create table as select", "distributed policy"}, fired)
+
+	res, fired = rewrites.Apply("SELECT 1")
+	require.Equal(t, "SELECT 1", res)
+	require.Empty(t, fired)
+}
+
+func TestQueryRewrites(t *testing.T) {
+	var rewrites QueryRewrites
+	require.NoError(t, rewrites.Load([]byte(`
+rewrites:
+  - name: limit
+    tokens: LIMIT ALL
+    replace: ""
+    tests:
+      - query: SELECT 1 limit /* comment */ all
+        result: 'SELECT 1 '
+      - query: SELECT 'LIMIT ALL' -- LIMIT ALL
+        result: SELECT 'LIMIT ALL' -- LIMIT ALL
+  - name: disabled
+    enabled: false
+    regexp: SELECT
+    replace: select
+  - name: nolock
+    regexp: '(?i)\s+FOR UPDATE$'
+    tests:
+      - query: SELECT 1 FOR UPDATE
+        result: SELECT 1
+`)))
+	require.Empty(t, rewrites.Test())
+
+	res, fired := rewrites.Apply("SELECT 1 LIMIT ALL FOR UPDATE")
+	require.Equal(t, "SELECT 1", res)
+	require.Equal(t, []string{"limit", "nolock"}, fired)
+
+	rewrites.Rewrites[2].Tests[0].Result = "SELECT 2"
+	require.Len(t, rewrites.Test(), 1)
+
+	for _, content := range []string{
+		"rewrites:\n  - name: empty\n",
+		"rewrites:\n  - name: both\n    regexp: a\n    tokens: a\n",
+		"rewrites:\n  - name: builtin\n    builtin: unknown\n",
+		"rewrites:\n  - name: dup\n    regexp: a\n  - name: dup\n    regexp: b\n",
+	} {
+		require.Error(t, (&QueryRewrites{}).Load([]byte(content)), content)
+	}
+}
+
+func TestCountRewrites(t *testing.T) {
+	var stats QueryStats
+	stats.CountRewrites([]string{"a", "b"}, true, "q1", 2)
+	stats.CountRewrites([]string{"a"}, false, "q2", 1)
+	stats.CountRewrites(nil, true, "q3", 1)
+
+	require.Equal(t, 2, stats.okRewrittenCount)
+	require.Equal(t, []rewriteStat{
+		{Name: "a", Count: 3, OkCount: 2, Example: "q1"},
+		{Name: "b", Count: 2, OkCount: 2, Example: "q1"},
+	}, stats.getRewritesNeedLock())
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+// replaySessions execute sessions in parallel, every session run on own database session.
+// Stats count transactions and sessions in own counters, issues of failed transactions counted as issues of queries.
+func replaySessions(rules Rules, stats *QueryStats, replayer internal.SessionReplayer, sessions <-chan internal.Session) {
+	if checkPgQueriesConfig.checkersCount < 1 {
+		log.Fatalf("can't start less then 1 checker, got: %v", checkPgQueriesConfig.checkersCount)
+	}
+
+	var counter atomic.Int64
+	needDeleteLine := false
+	var printMutex sync.Mutex
+
+	workers := checkPgQueriesConfig.checkersCount
+	if checkThroughput != nil {
+		workers = checkThroughput.Workers()
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for session := range sessions {
+				checkThroughput.Acquire()
+				replaySession(rules, stats, replayer, &session)
+				checkThroughput.Release()
+
+				current := counter.Add(1)
+				if current%int64(checkPgQueriesConfig.printProgressEveryQueries) == 0 {
+					printMutex.Lock()
+					if needDeleteLine {
+						printDeleteLine()
+					} else {
+						needDeleteLine = true
+					}
+					log.Printf("Replayed sessions %8d", current)
+					printMutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func replaySession(rules Rules, stats *QueryStats, replayer internal.SessionReplayer, session *internal.Session) {
+	if !checkPgQueriesConfig.includeFailed {
+		transactions := session.Transactions[:0]
+		for _, transaction := range session.Transactions {
+			if transaction.LogSuccess {
+				transactions = append(transactions, transaction)
+			}
+		}
+		session.Transactions = transactions
+		if len(transactions) == 0 {
+			return
+		}
+	}
+
+	originalTexts := make([]string, len(session.Transactions))
+	rewrites := make([][]string, len(session.Transactions))
+	for i := range session.Transactions {
+		originalTexts[i] = transactionText(session.Transactions[i])
+		for j := range session.Transactions[i].Queries {
+			q := &session.Transactions[i].Queries[j]
+			var fired []string
+			q.Text, fired = prepareQueryText(q.Text)
+			for _, name := range fired {
+				if !slices.Contains(rewrites[i], name) {
+					rewrites[i] = append(rewrites[i], name)
+				}
+			}
+		}
+	}
+
+	// retry session only if every transaction failed by overloaded or unavailable server,
+	// so the failed attempt has no committed changes
+	var txErrors []error
+	_ = configuredRetryPolicy().do(context.Background(), func(ctx context.Context) error {
+		txErrors = replayer.ReplaySession(ctx, session, checkThroughput.Wait)
+		return allRetryableError(txErrors)
+	})
+	hasFailed := false
+	for i, transaction := range session.Transactions {
+		checkThroughput.Observe(transaction.Latency, isRetryableError(txErrors[i]) || errors.Is(txErrors[i], context.DeadlineExceeded))
+		if transaction.Success {
+			stats.CountRewrites(rewrites[i], true, originalTexts[i], 1)
+			stats.CountTransaction("", checkResultOK, transactionText(transaction))
+			continue
+		}
+
+		queryText := transactionText(transaction)
+		var replayErr *internal.ReplayError
+		if errors.As(txErrors[i], &replayErr) {
+			queryText = replayErr.Query
+		}
+
+		outcome := newCheckOutcome(txErrors[i])
+		match := matchOutcome(rules, queryText, outcome)
+		reason, checkResult := classifyMatch(outcome, match)
+		stats.CountTransaction(reason, checkResult, queryText)
+		if checkResult != checkResultTransient {
+			// transient transactions have no verdict, so rewrites of them aren't counted
+			hasFailed = true
+			stats.CountRewrites(rewrites[i], false, originalTexts[i], 1)
+		}
+		if checkResult == checkResultErrUnknown {
+			stats.AddUnknownMessages(reason, normalizedIssueMessages(match.unknown))
+		}
+		countMatchedRules(stats, outcome, match, queryText, 1)
+		if checkPgQueriesConfig.printErrorsInProgress {
+			log.Printf("Session %v transaction %v failed: %v", session.ID, transaction.Number, txErrors[i])
+		}
+	}
+	switch {
+	case session.Success:
+		stats.CountSession(true)
+	case hasFailed:
+		stats.CountSession(false)
+	default:
+		// every failed transaction failed by overloaded or unavailable server or timeout
+		stats.CountTransientSession()
+	}
+}
+
+// allRetryableError return first error if every transaction failed with retryable error, else nil
+func allRetryableError(txErrors []error) error {
+	for _, err := range txErrors {
+		if !isRetryableError(err) {
+			return nil
+		}
+	}
+	if len(txErrors) == 0 {
+		return nil
+	}
+	return txErrors[0]
+}
+
+func transactionText(transaction internal.Transaction) string {
+	queries := make([]string, 0, len(transaction.Queries))
+	for _, q := range transaction.Queries {
+		queries = append(queries, q.Text)
+	}
+	return strings.Join(queries, ";\n")
+}
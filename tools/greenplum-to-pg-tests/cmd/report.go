@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var reportConfig struct {
+	statFile   string
+	rulesFile  string
+	outputFile string
+	title      string
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate static html compatibility report from stat file of check-pg-queries",
+	Run: func(cmd *cobra.Command, args []string) {
+		statFile, err := readStatFile(reportConfig.statFile)
+		if err != nil {
+			log.Fatalf("Failed to read stat file: %v", err)
+		}
+
+		var rules Rules
+		if reportConfig.rulesFile != "" {
+			if err = rules.LoadFromFile(reportConfig.rulesFile); err != nil {
+				log.Fatalf("Failed to read rules file: %v", err)
+			}
+		}
+
+		writer := openWriter(reportConfig.outputFile)
+		defer func() { _ = writer.Close() }()
+
+		if err = writeReport(writer, reportConfig.title, statFile, rules); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.PersistentFlags().StringVar(&reportConfig.statFile, "stat-file", "", "Path to stat file, written by check-pg-queries --write-stat-file")
+	reportCmd.PersistentFlags().StringVar(&reportConfig.rulesFile, "rules-file", "issues.yaml", "Rules file for issue links and tags. Set empty for skip read rules.")
+	reportCmd.PersistentFlags().StringVar(&reportConfig.outputFile, "output-file", "", "Path to html report. Stdout by default.")
+	reportCmd.PersistentFlags().StringVar(&reportConfig.title, "title", "PostgreSQL compatibility report", "Title of the report")
+	must0(reportCmd.MarkPersistentFlagRequired("stat-file"))
+}
+
+type reportKnownIssue struct {
+	Name      string
+	Count     int
+	Percent   float64
+	IssueLink string
+	Tags      string
+	Comment   string
+	Example   string
+}
+
+type reportData struct {
+	Title              string
+	GeneratedAt        string
+	Stat               queryStatFile
+	FailedCount        int
+	KnownIssues        []reportKnownIssue
+	UnknownIssues      []CounterWithExample[string]
+	SemanticMismatches []CounterWithExample[string]
+	BlockingSets       []reportBlockingSet
+}
+
+type reportBlockingSet struct {
+	Rules   string
+	Count   int
+	Example string
+}
+
+func writeReport(w io.Writer, title string, statFile queryStatFile, rules Rules) error {
+	rulesByName := make(map[string]PgIssueRules, len(rules.Issues))
+	for _, rule := range rules.Issues {
+		rulesByName[rule.Name] = rule
+	}
+
+	data := reportData{
+		Title:              title,
+		GeneratedAt:        time.Now().Format(time.DateTime),
+		Stat:               statFile,
+		FailedCount:        statFile.TotalCount - statFile.OkCount,
+		UnknownIssues:      statFile.UnknownIssues,
+		SemanticMismatches: statFile.SemanticMismatches,
+	}
+	for _, stat := range statFile.KnownIssues {
+		issue := reportKnownIssue{
+			Name:    stat.ID,
+			Count:   stat.Count,
+			Example: stat.Example,
+		}
+		if statFile.TotalCount > 0 {
+			issue.Percent = float64(stat.Count) / float64(statFile.TotalCount) * 100
+		}
+		if rule, ok := rulesByName[stat.ID]; ok {
+			issue.IssueLink = rule.IssueLink
+			issue.Tags = strings.Join(rule.Tag.Strings(), ", ")
+			issue.Comment = rule.Comment
+			if issue.Example == "" {
+				issue.Example = rule.Example
+			}
+		}
+		data.KnownIssues = append(data.KnownIssues, issue)
+	}
+	for _, set := range statFile.BlockingSets {
+		data.BlockingSets = append(data.BlockingSets, reportBlockingSet{
+			Rules:   strings.Join(set.Rules, " + "),
+			Count:   set.Unlocked,
+			Example: set.Example,
+		})
+	}
+
+	if err := reportTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// reportTemplate is self-contained page without external resources, for open report offline
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th.sortable { cursor: pointer; background: #f3f3f3; }
+th.sortable:after { content: " \2195"; color: #999; }
+td.number { text-align: right; }
+pre { margin: 0; white-space: pre-wrap; max-width: 80em; }
+.summary td { font-size: 1.2em; }
+.progress { width: 30em; height: 1.2em; background: #f2c9c9; }
+.progress div { height: 100%; background: #6cbf6c; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated at {{.GeneratedAt}}</p>
+
+<h2>Summary</h2>
+<div class="progress"><div style="width: {{printf "%.2f" .Stat.OkPercent}}%"></div></div>
+<table class="summary">
+<tr><th>Checked queries</th><td>{{.Stat.TotalCount}}</td></tr>
+<tr><th>Ok queries</th><td>{{.Stat.OkCount}} ({{printf "%.2f" .Stat.OkPercent}}%)</td></tr>
+<tr><th>Failed queries</th><td>{{.FailedCount}}</td></tr>
+{{if .Stat.DistinctCount}}<tr><th>Ok distinct queries</th><td>{{.Stat.DistinctOk}}/{{.Stat.DistinctCount}}</td></tr>{{end}}
+{{if .Stat.TotalTransactions}}<tr><th>Ok transactions</th><td>{{.Stat.OkTransactions}}/{{.Stat.TotalTransactions}}</td></tr>{{end}}
+{{if .Stat.TotalSessions}}<tr><th>Ok sessions</th><td>{{.Stat.OkSessions}}/{{.Stat.TotalSessions}}</td></tr>{{end}}
+{{if .Stat.TransientSessions}}<tr><th>Sessions not replayed by transient errors</th><td>{{.Stat.TransientSessions}}</td></tr>{{end}}
+{{if .Stat.ComparedCount}}<tr><th>Queries with same results</th><td>{{.Stat.ComparedCount}}</td></tr>{{end}}
+{{if .Stat.CompareSkipped}}<tr><th>Ok queries, which results not compared</th><td>{{.Stat.CompareSkipped}}</td></tr>{{end}}
+{{if .Stat.TransientCount}}<tr><th>Not checked by transient errors</th><td>{{.Stat.TransientCount}}</td></tr>{{end}}
+</table>
+
+<h2>Known issues</h2>
+<table class="sortable">
+<thead><tr>
+<th class="sortable">Issue</th><th class="sortable" data-type="number">Count</th><th class="sortable" data-type="number">% of queries</th>
+<th class="sortable">Tag</th><th class="sortable">Link</th><th>Example</th>
+</tr></thead>
+<tbody>
+{{range .KnownIssues}}<tr>
+<td>{{.Name}}{{if .Comment}}<br><small>{{.Comment}}</small>{{end}}</td>
+<td class="number" data-value="{{.Count}}">{{.Count}}</td>
+<td class="number" data-value="{{.Percent}}">{{printf "%.2f" .Percent}}</td>
+<td>{{.Tags}}</td>
+<td>{{if .IssueLink}}<a href="{{.IssueLink}}">{{.IssueLink}}</a>{{end}}</td>
+<td>{{if .Example}}<details><summary>show</summary><pre>{{.Example}}</pre></details>{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+
+{{if .BlockingSets}}<h2>Queries unlocked by fixes</h2>
+<p>Count of queries, which will pass after fix of the issues, include queries blocked by part of the issues</p>
+<table class="sortable">
+<thead><tr><th class="sortable">Issues</th><th class="sortable" data-type="number">Queries</th><th>Example</th></tr></thead>
+<tbody>
+{{range .BlockingSets}}<tr>
+<td>{{.Rules}}</td>
+<td class="number" data-value="{{.Count}}">{{.Count}}</td>
+<td>{{if .Example}}<details><summary>show</summary><pre>{{.Example}}</pre></details>{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+
+<h2>Unknown issues</h2>
+{{range .UnknownIssues}}<details>
+<summary>{{.Count}}: {{.ID}}</summary>
+<pre>{{.Example}}</pre>
+</details>
+{{else}}<p>No unknown issues</p>
+{{end}}
+
+{{if .SemanticMismatches}}<h2>Semantic mismatches</h2>
+{{range .SemanticMismatches}}<details>
+<summary>{{.Count}}: {{.ID}}</summary>
+<pre>{{.Example}}</pre>
+</details>
+{{end}}{{end}}
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th.sortable").forEach(function (th, column) {
+    var ascending = false;
+    th.addEventListener("click", function () {
+      var tbody = table.tBodies[0];
+      var rows = Array.prototype.slice.call(tbody.rows);
+      var isNumber = th.dataset.type === "number";
+      ascending = !ascending;
+      rows.sort(function (a, b) {
+        var cellA = a.cells[column], cellB = b.cells[column];
+        var res = isNumber
+          ? parseFloat(cellA.dataset.value) - parseFloat(cellB.dataset.value)
+          : cellA.textContent.localeCompare(cellB.textContent);
+        return ascending ? res : -res;
+      });
+      rows.forEach(function (row) { tbody.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
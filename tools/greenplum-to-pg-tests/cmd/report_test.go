@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReport(t *testing.T) {
+	statFile := queryStatFile{
+		TotalCount: 10,
+		OkCount:    6,
+		OkPercent:  60,
+		KnownIssues: []CounterWithExample[string]{
+			{ID: "is distinct", Count: 3, Example: "SELECT 1 IS DISTINCT FROM 2"},
+		},
+		UnknownIssues: []CounterWithExample[string]{
+			{ID: "strange <error>", Count: 1, Example: "SELECT 'a' < 'b'"},
+		},
+	}
+	rules := Rules{Issues: []PgIssueRules{
+		{Name: "is distinct", IssueLink: "https://github.com/ydb-platform/ydb/issues/7182", Tag: OneOrSliceString{"expr", "pg"}},
+	}}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeReport(buf, "Report", statFile, rules))
+
+	report := buf.String()
+	require.Contains(t, report, "60.00%")
+	require.Contains(t, report, `<a href="https://github.com/ydb-platform/ydb/issues/7182">`)
+	require.Contains(t, report, "expr, pg")
+	require.Contains(t, report, "30.00")
+	require.Contains(t, report, "1: strange &lt;error&gt;")
+	require.Contains(t, report, "SELECT &#39;a&#39; &lt; &#39;b&#39;")
+	require.NotContains(t, report, "http://", "report must not load external resources")
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+var rulesConfig struct {
+	rulesFile           string
+	ydbConnectionString string
+	failOnChanges       bool
+}
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Maintain issue rules file",
+}
+
+var rulesVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check examples of rules on ydb and report rules, which doesn't match own example or probably fixed",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		var rules Rules
+		if err := rules.LoadFromFile(rulesConfig.rulesFile); err != nil {
+			log.Fatalf("Failed to read rules file: %v", err)
+		}
+
+		connectCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+		checker := openYdbQueryChecker(connectCtx, rulesConfig.ydbConnectionString, internal.DefaultYdbPoolConfig())
+		cancel()
+		defer func() { _ = checker.Close(ctx) }()
+
+		results := verifyRules(ctx, checker, rules)
+		printRuleVerifyResults(os.Stdout, results)
+
+		if rulesConfig.failOnChanges && slices.ContainsFunc(results, func(res ruleVerifyResult) bool {
+			return res.Status != ruleVerifyConfirmed && res.Status != ruleVerifyNoExample
+		}) {
+			log.Fatalf("Some rules doesn't confirmed by examples")
+		}
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run tests of rules without server and detect rules shadowed by earlier rules",
+	Run: func(cmd *cobra.Command, args []string) {
+		var rules Rules
+		if err := rules.LoadFromFile(rulesConfig.rulesFile); err != nil {
+			log.Fatalf("Failed to read rules file: %v", err)
+		}
+
+		failures := testRules(rules)
+		for _, failure := range failures {
+			fmt.Println(failure)
+		}
+		if len(failures) > 0 {
+			log.Fatalf("Failed rule tests: %v", len(failures))
+		}
+		log.Println("All rule tests passed")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesVerifyCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+
+	rulesCmd.PersistentFlags().StringVar(&rulesConfig.rulesFile, "rules-file", "issues.yaml", "Rules file")
+	rulesVerifyCmd.PersistentFlags().StringVar(&rulesConfig.ydbConnectionString, "ydb-connection", "grpc://localhost:2136/local", "Connection string to ydb server for check examples")
+	rulesVerifyCmd.PersistentFlags().BoolVar(&rulesConfig.failOnChanges, "fail-on-changes", false, "Exit with error if some rule isn't confirmed by own example")
+}
+
+type ruleVerifyStatus string
+
+const (
+	ruleVerifyConfirmed     ruleVerifyStatus = "confirmed"
+	ruleVerifyNoExample     ruleVerifyStatus = "no example"
+	ruleVerifyFixed         ruleVerifyStatus = "probably fixed upstream"
+	ruleVerifyOtherRule     ruleVerifyStatus = "matched other rule"
+	ruleVerifyNotMatched    ruleVerifyStatus = "issues doesn't match the rule"
+	ruleVerifyNotYdbFailure ruleVerifyStatus = "failed without ydb issues"
+)
+
+type ruleVerifyResult struct {
+	Rule    string
+	Status  ruleVerifyStatus
+	Details string
+}
+
+// verifyRules check example of every rule and compare result issues with the rule
+func verifyRules(ctx context.Context, checker internal.QueryChecker, rules Rules) []ruleVerifyResult {
+	res := make([]ruleVerifyResult, 0, len(rules.Issues))
+	for _, rule := range rules.Issues {
+		res = append(res, verifyRule(ctx, checker, rules, rule))
+	}
+	return res
+}
+
+func verifyRule(ctx context.Context, checker internal.QueryChecker, rules Rules, rule PgIssueRules) ruleVerifyResult {
+	res := ruleVerifyResult{Rule: rule.Name}
+	if rule.Example == "" {
+		res.Status = ruleVerifyNoExample
+		return res
+	}
+
+	queryText, _ := prepareQueryText(rule.Example)
+	outcome := newCheckOutcome(checker.CheckQuery(ctx, queryText, queryParamTypes(queryText)))
+	switch {
+	case outcome.OK:
+		res.Status = ruleVerifyFixed
+		if rule.IssueLink != "" {
+			res.Details = rule.IssueLink
+		}
+		return res
+	case outcome.ErrorName == "":
+		res.Status = ruleVerifyNotYdbFailure
+		res.Details = outcome.RawError
+		return res
+	}
+
+	matched, unmatched := rules.MatchToKnownIssues(queryText, outcome.Issues)
+	for _, matchedRule := range matched {
+		if matchedRule.Name == rule.Name {
+			res.Status = ruleVerifyConfirmed
+			return res
+		}
+	}
+	if len(matched) > 0 {
+		res.Status = ruleVerifyOtherRule
+		res.Details = matched[0].Name
+		return res
+	}
+
+	res.Status = ruleVerifyNotMatched
+	res.Details = normalizedIssuesText(unmatched)
+	return res
+}
+
+func printRuleVerifyResults(w io.Writer, results []ruleVerifyResult) {
+	counts := map[ruleVerifyStatus]int{}
+	for _, res := range results {
+		counts[res.Status]++
+		if res.Status == ruleVerifyConfirmed || res.Status == ruleVerifyNoExample {
+			continue
+		}
+		if res.Details == "" {
+			fmt.Fprintf(w, "%v: %v\n", res.Rule, res.Status)
+		} else {
+			fmt.Fprintf(w, "%v: %v: %v\n", res.Rule, res.Status, res.Details)
+		}
+	}
+
+	fmt.Fprintln(w)
+	for _, status := range []ruleVerifyStatus{
+		ruleVerifyConfirmed, ruleVerifyNoExample, ruleVerifyFixed, ruleVerifyOtherRule, ruleVerifyNotMatched, ruleVerifyNotYdbFailure,
+	} {
+		fmt.Fprintf(w, "%v: %v\n", status, counts[status])
+	}
+}
+
+// testRules run tests of every rule and return descriptions of failures.
+// Test, which should match the rule, also fails if an earlier rule catch the issue first.
+func testRules(rules Rules) []string {
+	var failures []string
+	for _, rule := range rules.Issues {
+		for i, test := range rule.Tests {
+			issue := internal.YdbIssue{Message: test.Issue}
+			matched := rule.IsMatched(test.Query, issue)
+			switch {
+			case matched && !test.ShouldMatch():
+				failures = append(failures, fmt.Sprintf("%v: test %v: issue %q matched, but shouldn't", rule.Name, i, test.Issue))
+			case !matched && test.ShouldMatch():
+				failures = append(failures, fmt.Sprintf("%v: test %v: issue %q doesn't matched", rule.Name, i, test.Issue))
+			case matched:
+				knownIssues, _ := rules.MatchToKnownIssues(test.Query, []internal.YdbIssue{issue})
+				if len(knownIssues) > 0 && knownIssues[0].Name != rule.Name {
+					failures = append(failures, fmt.Sprintf("%v: test %v: shadowed by earlier rule %q", rule.Name, i, knownIssues[0].Name))
+				}
+			}
+		}
+	}
+	return failures
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// testQueryChecker return predefined errors by query text
+type testQueryChecker map[string]error
+
+func (c testQueryChecker) CheckQuery(ctx context.Context, queryText string, paramTypes []string) error {
+	return c[queryText]
+}
+
+func (c testQueryChecker) ExecQuery(ctx context.Context, queryText string) error {
+	return c[queryText]
+}
+
+func (c testQueryChecker) Version(ctx context.Context) (string, error) {
+	return "test", nil
+}
+
+func (c testQueryChecker) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestVerifyRules(t *testing.T) {
+	rules := Rules{Issues: []PgIssueRules{
+		{Name: "least", IssueRegexp: OneOrSliceString{"^least is not supported$"}, Example: "SELECT least(1, 2)"},
+		{Name: "fixed", IssueRegexp: OneOrSliceString{"^fixed$"}, Example: "SELECT 1", IssueLink: "https://example.com/1"},
+		{Name: "shadowed", IssueRegexp: OneOrSliceString{"^least"}, Example: "SELECT least(3, 4)"},
+		{Name: "changed", IssueRegexp: OneOrSliceString{"^old message$"}, Example: "SELECT changed()"},
+		{Name: "broken", IssueRegexp: OneOrSliceString{"^broken$"}, Example: "SELECT broken()"},
+		{Name: "without example", QueryRegexp: OneOrSliceString{"COMMIT"}},
+	}}
+	for i := range rules.Issues {
+		require.NoError(t, rules.Issues[i].Init())
+	}
+
+	checker := testQueryChecker{
+		"SELECT least(1, 2)": &pq.Error{Code: "0A000", Message: "least is not supported"},
+		"SELECT least(3, 4)": &pq.Error{Code: "0A000", Message: "least is not supported"},
+		"SELECT changed()":   &pq.Error{Code: "0A000", Message: "new message 12"},
+		"SELECT broken()":    errors.New("connection refused"),
+	}
+
+	results := verifyRules(context.Background(), checker, rules)
+	require.Equal(t, []ruleVerifyResult{
+		{Rule: "least", Status: ruleVerifyConfirmed},
+		{Rule: "fixed", Status: ruleVerifyFixed, Details: "https://example.com/1"},
+		{Rule: "shadowed", Status: ruleVerifyOtherRule, Details: "least"},
+		{Rule: "changed", Status: ruleVerifyNotMatched, Details: "new message ?"},
+		{Rule: "broken", Status: ruleVerifyNotYdbFailure, Details: "connection refused"},
+		{Rule: "without example", Status: ruleVerifyNoExample},
+	}, results)
+}
+
+func TestTestRules(t *testing.T) {
+	noMatch := false
+	rules := Rules{Issues: []PgIssueRules{
+		{
+			Name:        "wide",
+			IssueRegexp: OneOrSliceString{"^Cannot find"},
+			Tests: []RuleTest{
+				{Issue: "Cannot find table"},
+				{Issue: "Table not found", Match: &noMatch},
+			},
+		},
+		{
+			Name:        "table",
+			IssueRegexp: OneOrSliceString{"^Cannot find table"},
+			Tests:       []RuleTest{{Issue: "Cannot find table 'a'"}},
+		},
+		{
+			Name:        "insert",
+			IssueRegexp: OneOrSliceString{"not supported"},
+			QueryRegexp: OneOrSliceString{"(?i)^INSERT"},
+			Tests: []RuleTest{
+				{Query: "INSERT INTO t VALUES (1)", Issue: "onConflict not supported"},
+				{Query: "SELECT 1", Issue: "onConflict not supported", Match: &noMatch},
+				{Query: "SELECT 2", Issue: "onConflict not supported"},
+			},
+		},
+	}}
+	for i := range rules.Issues {
+		require.NoError(t, rules.Issues[i].Init())
+	}
+
+	require.Equal(t, []string{
+		`table: test 0: shadowed by earlier rule "wide"`,
+		`insert: test 2: issue "onConflict not supported" doesn't matched`,
+	}, testRules(rules))
+}
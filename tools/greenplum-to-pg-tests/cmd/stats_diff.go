@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var statsDiffConfig struct {
+	regressionThreshold float64
+}
+
+var statsDiffCmd = &cobra.Command{
+	Use:   "stats-diff old-stat.yaml new-stat.yaml",
+	Short: "Compare stat files of two check-pg-queries runs",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		oldStat, err := readStatFile(args[0])
+		if err != nil {
+			log.Fatalf("Failed to read old stat: %v", err)
+		}
+		newStat, err := readStatFile(args[1])
+		if err != nil {
+			log.Fatalf("Failed to read new stat: %v", err)
+		}
+
+		diff, err := diffStats(oldStat, newStat)
+		if err != nil {
+			log.Fatalf("Failed to compare stats: %v", err)
+		}
+		printStatsDiff(os.Stdout, diff)
+
+		if regressions := diff.regressions(statsDiffConfig.regressionThreshold); len(regressions) > 0 {
+			log.Fatalf("Regressions above threshold %.2f points:\n%v", statsDiffConfig.regressionThreshold, strings.Join(regressions, "\n"))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsDiffCmd)
+
+	statsDiffCmd.PersistentFlags().Float64Var(&statsDiffConfig.regressionThreshold, "regression-threshold", 0, "Exit with error if ok percent decreased or percent of queries with a known issue increased more than the threshold (in percent points)")
+}
+
+// counterDelta compare counter of two runs, rates are percents of total queries of the run,
+// so runs with different count of queries comparable
+type counterDelta struct {
+	ID       string
+	OldCount int
+	NewCount int
+	OldRate  float64
+	NewRate  float64
+	Example  string
+}
+
+func (d counterDelta) delta() int {
+	return d.NewCount - d.OldCount
+}
+
+func (d counterDelta) rateDelta() float64 {
+	return d.NewRate - d.OldRate
+}
+
+type statsDiff struct {
+	OldTotal     int
+	NewTotal     int
+	OldOkPercent float64
+	NewOkPercent float64
+
+	Fixed              []counterDelta // known issues with decreased rate
+	Regressed          []counterDelta // known issues with increased rate
+	AppearedUnknown    []counterDelta
+	DisappearedUnknown []counterDelta
+
+	// FirstMatchOnly is set if a stat file has no all matches counters and known issues compared by first matched rule:
+	// fix of higher priority rule move its queries to next matched rule, so rate of the rule increase without regression
+	FirstMatchOnly bool
+}
+
+func (d statsDiff) okPercentDelta() float64 {
+	return d.NewOkPercent - d.OldOkPercent
+}
+
+// regressions return descriptions of ok percent decrease and known issues rate increase above the threshold.
+// Rates of first matched rules are regressions only together with decrease of ok percent.
+func (d statsDiff) regressions(threshold float64) []string {
+	var res []string
+	if -d.okPercentDelta() > threshold {
+		res = append(res, fmt.Sprintf("ok percent: %.2f -> %.2f (%+.2f)", d.OldOkPercent, d.NewOkPercent, d.okPercentDelta()))
+	}
+	if d.FirstMatchOnly && d.okPercentDelta() >= 0 {
+		return res
+	}
+	for _, item := range d.Regressed {
+		if item.rateDelta() > threshold {
+			res = append(res, fmt.Sprintf("%v: %.2f%% -> %.2f%% (%+.2f)", item.ID, item.OldRate, item.NewRate, item.rateDelta()))
+		}
+	}
+	return res
+}
+
+func diffStats(oldStat, newStat queryStatFile) (statsDiff, error) {
+	// ok_percent is NaN for run without checked queries
+	if math.IsNaN(oldStat.OkPercent) || oldStat.TotalCount == 0 {
+		return statsDiff{}, fmt.Errorf("old stat has no checked queries, ok percent: %v", oldStat.OkPercent)
+	}
+	if math.IsNaN(newStat.OkPercent) || newStat.TotalCount == 0 {
+		return statsDiff{}, fmt.Errorf("new stat has no checked queries, ok percent: %v", newStat.OkPercent)
+	}
+
+	res := statsDiff{
+		OldTotal:     oldStat.TotalCount,
+		NewTotal:     newStat.TotalCount,
+		OldOkPercent: oldStat.OkPercent,
+		NewOkPercent: newStat.OkPercent,
+	}
+
+	oldRules, newRules := oldStat.AllMatches, newStat.AllMatches
+	if !hasAllMatches(oldStat) || !hasAllMatches(newStat) {
+		oldRules, newRules = oldStat.KnownIssues, newStat.KnownIssues
+		res.FirstMatchOnly = true
+	}
+	for _, known := range diffCounters(oldRules, newRules, oldStat.TotalCount, newStat.TotalCount) {
+		switch {
+		case known.rateDelta() < 0:
+			res.Fixed = append(res.Fixed, known)
+		case known.rateDelta() > 0:
+			res.Regressed = append(res.Regressed, known)
+		}
+	}
+
+	for _, unknown := range diffCounters(oldStat.UnknownIssues, newStat.UnknownIssues, oldStat.TotalCount, newStat.TotalCount) {
+		switch {
+		case unknown.OldCount == 0:
+			res.AppearedUnknown = append(res.AppearedUnknown, unknown)
+		case unknown.NewCount == 0:
+			res.DisappearedUnknown = append(res.DisappearedUnknown, unknown)
+		}
+	}
+	return res, nil
+}
+
+// hasAllMatches check that counters of every matched rule written to the stat file,
+// stat files of old versions have first matched rule counters only
+func hasAllMatches(stat queryStatFile) bool {
+	return len(stat.AllMatches) > 0 || len(stat.KnownIssues) == 0
+}
+
+// diffCounters join counters by id, result sorted by absolute delta of rate
+func diffCounters(oldCounters, newCounters []CounterWithExample[string], oldTotal, newTotal int) []counterDelta {
+	byID := map[string]*counterDelta{}
+	get := func(id string) *counterDelta {
+		if byID[id] == nil {
+			byID[id] = &counterDelta{ID: id}
+		}
+		return byID[id]
+	}
+	for _, counter := range oldCounters {
+		item := get(counter.ID)
+		item.OldCount = counter.Count
+		item.Example = counter.Example
+	}
+	for _, counter := range newCounters {
+		item := get(counter.ID)
+		item.NewCount = counter.Count
+		item.Example = counter.Example
+	}
+
+	res := make([]counterDelta, 0, len(byID))
+	for _, item := range byID {
+		item.OldRate = float64(item.OldCount) * 100 / float64(oldTotal)
+		item.NewRate = float64(item.NewCount) * 100 / float64(newTotal)
+		res = append(res, *item)
+	}
+	slices.SortFunc(res, func(a, b counterDelta) int {
+		return cmp.Or(
+			cmp.Compare(math.Abs(b.rateDelta()), math.Abs(a.rateDelta())),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+	return res
+}
+
+func printStatsDiff(w io.Writer, diff statsDiff) {
+	fmt.Fprintf(w, "Total queries: %v -> %v\n", diff.OldTotal, diff.NewTotal)
+	fmt.Fprintf(w, "Ok percent: %.2f -> %.2f (%+.2f)\n", diff.OldOkPercent, diff.NewOkPercent, diff.okPercentDelta())
+
+	printDeltas := func(title string, deltas []counterDelta) {
+		if len(deltas) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%v:\n", title)
+		for _, item := range deltas {
+			fmt.Fprintf(w, "  %v: %v -> %v (%+d), %.2f%% -> %.2f%% (%+.2f)\n", item.ID, item.OldCount, item.NewCount, item.delta(), item.OldRate, item.NewRate, item.rateDelta())
+		}
+	}
+	printDeltas("Fixed known issues", diff.Fixed)
+	printDeltas("Regressed known issues", diff.Regressed)
+	printDeltas("New unknown issues", diff.AppearedUnknown)
+	printDeltas("Disappeared unknown issues", diff.DisappearedUnknown)
+}
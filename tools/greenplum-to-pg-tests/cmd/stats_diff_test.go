@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffStats(t *testing.T) {
+	oldStat := queryStatFile{
+		TotalCount: 100,
+		OkPercent:  60,
+		KnownIssues: []CounterWithExample[string]{
+			{ID: "least", Count: 10},
+			{ID: "is distinct", Count: 5},
+			{ID: "same", Count: 3},
+		},
+		UnknownIssues: []CounterWithExample[string]{
+			{ID: "old error", Count: 2},
+			{ID: "stable error", Count: 1},
+		},
+	}
+	// twice more queries, counts compared as percents of total
+	newStat := queryStatFile{
+		TotalCount: 200,
+		OkPercent:  58.5,
+		KnownIssues: []CounterWithExample[string]{
+			{ID: "least", Count: 4},
+			{ID: "is distinct", Count: 14},
+			{ID: "same", Count: 6},
+			{ID: "new rule", Count: 1},
+		},
+		UnknownIssues: []CounterWithExample[string]{
+			{ID: "new error", Count: 8},
+			{ID: "stable error", Count: 2},
+		},
+	}
+
+	diff, err := diffStats(oldStat, newStat)
+	require.NoError(t, err)
+	require.Equal(t, []counterDelta{{ID: "least", OldCount: 10, NewCount: 4, OldRate: 10, NewRate: 2}}, diff.Fixed)
+	require.Equal(t, []counterDelta{
+		{ID: "is distinct", OldCount: 5, NewCount: 14, OldRate: 5, NewRate: 7},
+		{ID: "new rule", OldCount: 0, NewCount: 1, OldRate: 0, NewRate: 0.5},
+	}, diff.Regressed)
+	require.Equal(t, []counterDelta{{ID: "new error", NewCount: 8, NewRate: 4}}, diff.AppearedUnknown)
+	require.Equal(t, []counterDelta{{ID: "old error", OldCount: 2, OldRate: 2}}, diff.DisappearedUnknown)
+
+	require.InDelta(t, -1.5, diff.okPercentDelta(), 0.0001)
+	require.Equal(t, []string{
+		"ok percent: 60.00 -> 58.50 (-1.50)",
+		"is distinct: 5.00% -> 7.00% (+2.00)",
+	}, diff.regressions(1))
+	require.Equal(t, []string{"is distinct: 5.00% -> 7.00% (+2.00)"}, diff.regressions(1.5))
+	require.Empty(t, diff.regressions(2))
+
+	buf := &bytes.Buffer{}
+	printStatsDiff(buf, diff)
+	require.Contains(t, buf.String(), "Ok percent: 60.00 -> 58.50 (-1.50)")
+	require.Contains(t, buf.String(), "least: 10 -> 4 (-6), 10.00% -> 2.00% (-8.00)")
+}
+
+func TestDiffStatsFixedHigherPriorityRule(t *testing.T) {
+	// queries with both issues counted for higher priority rule before the fix and for next rule after it
+	oldStat := queryStatFile{
+		TotalCount:  100,
+		OkPercent:   80,
+		KnownIssues: []CounterWithExample[string]{{ID: "high", Count: 10}, {ID: "low", Count: 10}},
+		AllMatches:  []CounterWithExample[string]{{ID: "high", Count: 10}, {ID: "low", Count: 15}},
+	}
+	newStat := queryStatFile{
+		TotalCount:  100,
+		OkPercent:   85,
+		KnownIssues: []CounterWithExample[string]{{ID: "low", Count: 15}},
+		AllMatches:  []CounterWithExample[string]{{ID: "low", Count: 15}},
+	}
+
+	diff, err := diffStats(oldStat, newStat)
+	require.NoError(t, err)
+	require.False(t, diff.FirstMatchOnly)
+	require.Equal(t, []counterDelta{{ID: "high", OldCount: 10, OldRate: 10}}, diff.Fixed)
+	require.Empty(t, diff.Regressed)
+	require.Empty(t, diff.regressions(0))
+
+	// without all matches rate of first matched rule increased, but ok percent isn't decreased
+	oldStat.AllMatches, newStat.AllMatches = nil, nil
+	diff, err = diffStats(oldStat, newStat)
+	require.NoError(t, err)
+	require.True(t, diff.FirstMatchOnly)
+	require.Len(t, diff.Regressed, 1)
+	require.Empty(t, diff.regressions(0))
+}
+
+func TestDiffStatsWithoutQueries(t *testing.T) {
+	checked := queryStatFile{TotalCount: 10, OkCount: 5, OkPercent: 50}
+	empty := queryStatFile{OkPercent: math.NaN()}
+
+	_, err := diffStats(checked, empty)
+	require.Error(t, err)
+	_, err = diffStats(empty, checked)
+	require.Error(t, err)
+	_, err = diffStats(checked, queryStatFile{})
+	require.Error(t, err)
+}
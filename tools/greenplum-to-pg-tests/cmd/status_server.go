@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+const statusTopIssues = 20
+
+// unknownReasonOther is label value of unknown issues metric for reasons out of top, it bounds cardinality of the metric
+const unknownReasonOther = "other"
+
+// checkLatency observed for every query check, served by /metrics when http-listen set
+var checkLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "pg_queries_check_duration_seconds",
+	Help:    "Duration of check one query on the checker server",
+	Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+})
+
+// inflightReporter implemented by checkers with pool of connections
+type inflightReporter interface {
+	Inflight() []int
+}
+
+// statusSnapshot is consistent copy of stats for status page and metrics
+type statusSnapshot struct {
+	Total           int
+	Ok              int
+	OkPercent       float64
+	Distinct        int
+	DistinctOk      int
+	Transactions    int
+	TransactionsOk  int
+	Sessions        int
+	SessionsOk      int
+	Compared        int
+	CompareSkipped  int
+	Known           []CounterWithExample[string]
+	Unknown         []CounterWithExample[string]
+	UnknownTotal    int
+	MismatchesTotal int
+	Transient       int
+	UpdatedAt       time.Time
+}
+
+// statusSnapshot copy counters, top is limit of known and unknown issues, 0 mean all
+func (s *QueryStats) statusSnapshot(top int) statusSnapshot {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	if top <= 0 {
+		top = len(s.MatchToRules) + len(s.UnknownProblems)
+	}
+
+	res := statusSnapshot{
+		Total:          s.totalCount,
+		Ok:             s.okCount,
+		OkPercent:      s.getOkPercentNeedLock(),
+		Distinct:       s.distinctCount,
+		DistinctOk:     s.distinctOkCount,
+		Sessions:       s.sessionsCount,
+		SessionsOk:     s.sessionsOkCount,
+		Transactions:   s.transactionsCount,
+		TransactionsOk: s.transactionsOkCount,
+		Compared:       s.comparedCount,
+		CompareSkipped: s.compareSkippedCount,
+		Transient:      s.transientCount,
+		Known:          s.getTopKnownNeedLock(top),
+		Unknown:        s.getTopUnknownNeedLock(top),
+		UpdatedAt:      time.Now(),
+	}
+	for _, stat := range s.UnknownProblems {
+		res.UnknownTotal += stat.Count
+	}
+	for _, stat := range s.SemanticMismatches {
+		res.MismatchesTotal += stat.Count
+	}
+	return res
+}
+
+// queryStatsCollector export current stats as prometheus metrics on every scrape
+type queryStatsCollector struct {
+	stats    *QueryStats
+	inflight inflightReporter
+
+	total          *prometheus.Desc
+	ok             *prometheus.Desc
+	known          *prometheus.Desc
+	unknown        *prometheus.Desc
+	mismatches     *prometheus.Desc
+	transient      *prometheus.Desc
+	distinct       *prometheus.Desc
+	distinctOk     *prometheus.Desc
+	sessions       *prometheus.Desc
+	sessionsOk     *prometheus.Desc
+	transactions   *prometheus.Desc
+	transactionsOk *prometheus.Desc
+	driver         *prometheus.Desc
+}
+
+func newQueryStatsCollector(stats *QueryStats, inflight inflightReporter) *queryStatsCollector {
+	return &queryStatsCollector{
+		stats:          stats,
+		inflight:       inflight,
+		total:          prometheus.NewDesc("pg_queries_checked_total", "Checked queries", nil, nil),
+		ok:             prometheus.NewDesc("pg_queries_ok_total", "Successfully checked queries", nil, nil),
+		known:          prometheus.NewDesc("pg_queries_known_issues_total", "Failed queries matched to rule", []string{"rule"}, nil),
+		unknown:        prometheus.NewDesc("pg_queries_unknown_issues_total", "Failed queries without matched rule, top reasons by count and sum of others", []string{"reason"}, nil),
+		mismatches:     prometheus.NewDesc("pg_queries_semantic_mismatches_total", "Queries with different results in diff mode", nil, nil),
+		transient:      prometheus.NewDesc("pg_queries_transient_errors_total", "Queries, which isn't checked because of overloaded or unavailable server or timeout", nil, nil),
+		distinct:       prometheus.NewDesc("pg_queries_distinct_total", "Checked distinct query fingerprints", nil, nil),
+		distinctOk:     prometheus.NewDesc("pg_queries_distinct_ok_total", "Successfully checked distinct query fingerprints", nil, nil),
+		sessions:       prometheus.NewDesc("pg_queries_sessions_total", "Replayed sessions", nil, nil),
+		sessionsOk:     prometheus.NewDesc("pg_queries_sessions_ok_total", "Successfully replayed sessions", nil, nil),
+		transactions:   prometheus.NewDesc("pg_queries_transactions_total", "Replayed transactions", nil, nil),
+		transactionsOk: prometheus.NewDesc("pg_queries_transactions_ok_total", "Successfully replayed transactions", nil, nil),
+		driver:         prometheus.NewDesc("pg_queries_driver_inflight", "Current inflight queries of connection", []string{"driver"}, nil),
+	}
+}
+
+func (c *queryStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.total, c.ok, c.known, c.unknown, c.mismatches, c.transient, c.distinct, c.distinctOk, c.sessions, c.sessionsOk, c.transactions, c.transactionsOk, c.driver,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *queryStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := c.stats.statusSnapshot(0)
+
+	counter := func(desc *prometheus.Desc, value int, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), labels...)
+	}
+	counter(c.total, snapshot.Total)
+	counter(c.ok, snapshot.Ok)
+	counter(c.mismatches, snapshot.MismatchesTotal)
+	counter(c.transient, snapshot.Transient)
+	counter(c.distinct, snapshot.Distinct)
+	counter(c.distinctOk, snapshot.DistinctOk)
+	counter(c.sessions, snapshot.Sessions)
+	counter(c.sessionsOk, snapshot.SessionsOk)
+	counter(c.transactions, snapshot.Transactions)
+	counter(c.transactionsOk, snapshot.TransactionsOk)
+	for _, known := range snapshot.Known {
+		counter(c.known, known.Count, known.ID)
+	}
+	other := snapshot.UnknownTotal
+	for _, unknown := range snapshot.Unknown[:min(len(snapshot.Unknown), statusTopIssues)] {
+		counter(c.unknown, unknown.Count, unknown.ID)
+		other -= unknown.Count
+	}
+	if other > 0 {
+		counter(c.unknown, other, unknownReasonOther)
+	}
+
+	if c.inflight != nil {
+		for i, inflight := range c.inflight.Inflight() {
+			ch <- prometheus.MustNewConstMetric(c.driver, prometheus.GaugeValue, float64(inflight), strconv.Itoa(i))
+		}
+	}
+}
+
+var statusPageTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>check-pg-queries</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { margin: 0; white-space: pre-wrap; max-width: 80em; }
+</style>
+</head>
+<body>
+<h1>check-pg-queries</h1>
+<p>Updated at {{.UpdatedAt.Format "2006-01-02 15:04:05"}}</p>
+<table>
+<tr><th>Checked</th><td>{{.Total}}</td></tr>
+<tr><th>Ok</th><td>{{.Ok}} ({{printf "%.2f" .OkPercent}}%)</td></tr>
+<tr><th>Unknown</th><td>{{.UnknownTotal}}</td></tr>
+{{if .Distinct}}<tr><th>Distinct ok</th><td>{{.DistinctOk}}/{{.Distinct}}</td></tr>{{end}}
+{{if .Transactions}}<tr><th>Transactions ok</th><td>{{.TransactionsOk}}/{{.Transactions}}</td></tr>{{end}}
+{{if .Sessions}}<tr><th>Sessions ok</th><td>{{.SessionsOk}}/{{.Sessions}}</td></tr>{{end}}
+{{if .Compared}}<tr><th>Same results</th><td>{{.Compared}}</td></tr>{{end}}
+{{if .CompareSkipped}}<tr><th>Skipped compares</th><td>{{.CompareSkipped}}</td></tr>{{end}}
+{{if .MismatchesTotal}}<tr><th>Semantic mismatches</th><td>{{.MismatchesTotal}}</td></tr>{{end}}
+{{if .Transient}}<tr><th>Transient errors</th><td>{{.Transient}}</td></tr>{{end}}
+</table>
+<h2>Top known issues</h2>
+<table>
+<tr><th>Rule</th><th>Count</th><th>Example</th></tr>
+{{range .Known}}<tr><td>{{.ID}}</td><td>{{.Count}}</td><td><pre>{{.Example}}</pre></td></tr>
+{{end}}</table>
+<h2>Top unknown issues</h2>
+<table>
+<tr><th>Problem</th><th>Count</th><th>Example</th></tr>
+{{range .Unknown}}<tr><td><pre>{{.ID}}</pre></td><td>{{.Count}}</td><td><pre>{{.Example}}</pre></td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func newStatusHandler(stats *QueryStats, inflight inflightReporter) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newQueryStatsCollector(stats, inflight), checkLatency)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := statusPageTemplate.Execute(w, stats.statusSnapshot(statusTopIssues)); err != nil {
+			log.Printf("Failed to render status page: %v", err)
+		}
+	})
+	return mux
+}
+
+// startStatusServer serve status page and metrics in background until process exit
+func startStatusServer(listen string, stats *QueryStats, checker internal.QueryChecker) {
+	inflight, _ := checker.(inflightReporter)
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           newStatusHandler(stats, inflight),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf("Serve status page and metrics on %q", listen)
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("Status server stopped: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testInflight []int
+
+func (i testInflight) Inflight() []int {
+	return i
+}
+
+func TestStatusHandler(t *testing.T) {
+	var stats QueryStats
+	stats.CountASOK("SELECT 1", 3)
+	stats.CountAsKnown("unsupported-func", "SELECT f()", 2)
+	stats.CountAsUnknown("strange error", "SELECT <b>", 5)
+	for i := range statusTopIssues {
+		stats.CountAsUnknown(fmt.Sprintf("rare error %v", i), "SELECT 2", 1)
+	}
+
+	server := httptest.NewServer(newStatusHandler(&stats, testInflight{1, 0}))
+	defer server.Close()
+
+	get := func(path string) string {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	metrics := get("/metrics")
+	require.Contains(t, metrics, "pg_queries_checked_total 30")
+	require.Contains(t, metrics, "pg_queries_ok_total 3")
+	require.Contains(t, metrics, `pg_queries_known_issues_total{rule="unsupported-func"} 2`)
+	require.Contains(t, metrics, `pg_queries_unknown_issues_total{reason="strange error"} 5`)
+	require.Contains(t, metrics, `pg_queries_unknown_issues_total{reason="other"} 1`)
+	require.Equal(t, statusTopIssues+1, strings.Count(metrics, "pg_queries_unknown_issues_total{"))
+	require.Contains(t, metrics, `pg_queries_driver_inflight{driver="0"} 1`)
+	require.Contains(t, metrics, "pg_queries_check_duration_seconds_bucket")
+
+	page := get("/")
+	require.Contains(t, page, "unsupported-func")
+	require.Contains(t, page, "SELECT &lt;b&gt;")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+var suggestRulesConfig struct {
+	statFile   string
+	outputFile string
+	minCount   int
+	nameLength int
+}
+
+var suggestRulesCmd = &cobra.Command{
+	Use:   "suggest-rules",
+	Short: "Cluster unknown issues from stat file and write draft rules for issues.yaml",
+	Run: func(cmd *cobra.Command, args []string) {
+		statFile, err := readStatFile(suggestRulesConfig.statFile)
+		if err != nil {
+			log.Fatalf("Failed to read stat file: %v", err)
+		}
+
+		rules := suggestRules(statFile.UnknownIssues, statFile.UnknownMessages, suggestRulesConfig.minCount, suggestRulesConfig.nameLength)
+		log.Printf("Suggested rules: %v", len(rules))
+
+		writer := openWriter(suggestRulesConfig.outputFile)
+		defer func() { _ = writer.Close() }()
+
+		if err = writeSuggestedRules(writer, rules); err != nil {
+			log.Fatalf("Failed to write rules: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(suggestRulesCmd)
+
+	suggestRulesCmd.PersistentFlags().StringVar(&suggestRulesConfig.statFile, "stat-file", "", "Path to stat file, written by check-pg-queries --write-stat-file")
+	suggestRulesCmd.PersistentFlags().StringVar(&suggestRulesConfig.outputFile, "output-file", "", "Path to write draft rules. Stdout by default.")
+	suggestRulesCmd.PersistentFlags().IntVar(&suggestRulesConfig.minCount, "min-count", 1, "Skip clusters with less queries")
+	suggestRulesCmd.PersistentFlags().IntVar(&suggestRulesConfig.nameLength, "name-length", 80, "Max length of draft rule name")
+	must0(suggestRulesCmd.MarkPersistentFlagRequired("stat-file"))
+}
+
+// suggestRules group unknown issues by normalized message, every group become draft rule
+// with regexp for the message, summary count and shortest query example.
+// messages contains normalized issue messages by unknown reason, reasons without messages (not ydb errors) are skipped.
+func suggestRules(unknownIssues []CounterWithExample[string], messages map[string][]string, minCount int, nameLength int) []PgIssueRules {
+	clusters := map[string]*PgIssueRules{}
+	for _, unknown := range unknownIssues {
+		seen := map[string]bool{}
+		for _, normalized := range messages[unknown.ID] {
+			if normalized == "" || seen[normalized] {
+				continue
+			}
+			seen[normalized] = true
+
+			rule := clusters[normalized]
+			if rule == nil {
+				rule = &PgIssueRules{
+					Name:        draftRuleName(normalized, nameLength),
+					IssueRegexp: OneOrSliceString{internal.IssueMessageRegexp(normalized)},
+					Example:     unknown.Example,
+					Comment:     "draft: " + normalized,
+				}
+				clusters[normalized] = rule
+			}
+			rule.Count += unknown.Count
+			if len(unknown.Example) < len(rule.Example) {
+				rule.Example = unknown.Example
+			}
+		}
+	}
+
+	res := make([]PgIssueRules, 0, len(clusters))
+	for _, rule := range clusters {
+		if rule.Count >= minCount {
+			res = append(res, *rule)
+		}
+	}
+	slices.SortFunc(res, func(a, b PgIssueRules) int {
+		return cmp.Or(
+			cmp.Compare(b.Count, a.Count),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	// names must be unique in rules file
+	names := map[string]int{}
+	for i := range res {
+		names[res[i].Name]++
+		if count := names[res[i].Name]; count > 1 {
+			res[i].Name = fmt.Sprintf("%v (%v)", res[i].Name, count)
+		}
+	}
+	return res
+}
+
+func draftRuleName(normalized string, nameLength int) string {
+	name := strings.Join(strings.Fields(normalized), " ")
+	if runes := []rune(name); nameLength > 0 && len(runes) > nameLength {
+		name = strings.TrimSpace(string(runes[:nameLength])) + "..."
+	}
+	return name
+}
+
+func writeSuggestedRules(w io.Writer, rules []PgIssueRules) error {
+	for i := range rules {
+		rules[i].Example = cleanStringForLiteralYaml(rules[i].Example)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(rules); err != nil {
+		return fmt.Errorf("failed to encode rules: %w", err)
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+func TestSuggestRules(t *testing.T) {
+	var rules Rules
+	reason := func(message string) string {
+		r, checkResult := classifyOutcome(rules, "", checkOutcome{
+			ErrorName: "GENERIC_ERROR (400080)",
+			Issues:    []internal.YdbIssue{{Message: message}},
+		})
+		require.Equal(t, checkResultErrUnknown, checkResult)
+		return r
+	}
+
+	require.Equal(t, reason("Cannot find column 'a' at 1:2"), reason("Cannot find column 'bb' at 10:20"))
+
+	var stats QueryStats
+	twoIssues := []internal.YdbIssue{{Message: "Cannot find column 'b' at 3:4"}, {Message: "Other problem; with separator"}}
+	stats.AddUnknownMessages(reason("Cannot find column 'a' at 1:2"), normalizedIssueMessages([]internal.YdbIssue{{Message: "Cannot find column 'a' at 1:2"}}))
+	stats.AddUnknownMessages("GENERIC_ERROR (400080): two issues", normalizedIssueMessages(twoIssues))
+
+	unknownIssues := []CounterWithExample[string]{
+		{ID: reason("Cannot find column 'a' at 1:2"), Count: 3, Example: "SELECT a FROM t"},
+		{ID: "GENERIC_ERROR (400080): two issues", Count: 2, Example: "SELECT b FROM t2"},
+		{ID: "non ydb err: timeout", Count: 10, Example: "SELECT 1"},
+	}
+	suggested := suggestRules(unknownIssues, stats.UnknownMessages, 1, 80)
+	require.Len(t, suggested, 2)
+	require.Equal(t, "Cannot find column '?' at ?:?", suggested[0].Name)
+	require.Equal(t, 5, suggested[0].Count)
+	require.Equal(t, "SELECT a FROM t", suggested[0].Example)
+	require.Equal(t, "Other problem; with separator", suggested[1].Name)
+
+	// draft must be valid rule, which match original issues
+	require.NoError(t, suggested[0].Init())
+	require.True(t, suggested[0].IsMatched("SELECT c FROM t", internal.YdbIssue{Message: "Cannot find column 'c' at 5:6"}))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeSuggestedRules(buf, suggested))
+	var parsed []PgIssueRules
+	require.NoError(t, yaml.Unmarshal(buf.Bytes(), &parsed))
+	require.Len(t, parsed, 2)
+
+	require.Len(t, suggestRules(unknownIssues, stats.UnknownMessages, 3, 80), 1)
+}
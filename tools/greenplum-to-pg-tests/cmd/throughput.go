@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// checkThroughput limit rate and concurrency of checks, nil mean without limits
+var checkThroughput *throughputController
+
+// adaptInterval is minimal interval between changes of adaptive concurrency limit
+const adaptInterval = time.Second
+
+// throughputController limit queries per second by token bucket and count of concurrent checks.
+// In adaptive mode the concurrency limit grows while latency is below target and checkers are busy,
+// and shrinks on overload errors or high latency.
+type throughputController struct {
+	bucket *tokenBucket // nil if qps is unlimited
+
+	adaptive      bool
+	maxLimit      int
+	targetLatency time.Duration
+	now           func() time.Time
+
+	m      sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+
+	windowStart     time.Time
+	windowCount     int
+	windowLatency   time.Duration
+	windowOverloads int
+	windowBusy      bool // all slots were used in the window
+
+	lastQps     float64
+	lastLatency time.Duration
+}
+
+func newThroughputController(maxQps float64, parallel int, adaptive bool, maxParallel int, targetLatency time.Duration) *throughputController {
+	res := &throughputController{
+		adaptive:      adaptive,
+		maxLimit:      max(parallel, maxParallel),
+		targetLatency: targetLatency,
+		now:           time.Now,
+		limit:         parallel,
+	}
+	if !adaptive {
+		res.maxLimit = parallel
+	}
+	if maxQps > 0 {
+		res.bucket = newTokenBucket(maxQps)
+	}
+	res.cond = sync.NewCond(&res.m)
+	res.windowStart = res.now()
+	return res
+}
+
+// Workers return count of worker goroutines, some of them may wait for free slot
+func (c *throughputController) Workers() int {
+	return c.maxLimit
+}
+
+// Acquire wait until count of active checks is less than current limit
+func (c *throughputController) Acquire() {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for c.active >= c.limit {
+		c.cond.Wait()
+	}
+	c.active++
+	if c.active >= c.limit {
+		c.windowBusy = true
+	}
+}
+
+func (c *throughputController) Release() {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.active--
+	c.cond.Signal()
+}
+
+// Wait for token of qps limit before send query to server
+func (c *throughputController) Wait(ctx context.Context) {
+	if c == nil || c.bucket == nil {
+		return
+	}
+
+	delay := c.bucket.reserve(c.now())
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// Observe latency of sent query, overloaded is true if server answered by overload or unavailable error or timeout
+func (c *throughputController) Observe(latency time.Duration, overloaded bool) {
+	if c == nil {
+		return
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.windowCount++
+	c.windowLatency += latency
+	if overloaded {
+		c.windowOverloads++
+	}
+
+	now := c.now()
+	elapsed := now.Sub(c.windowStart)
+	if elapsed < adaptInterval {
+		return
+	}
+
+	c.lastQps = float64(c.windowCount) / elapsed.Seconds()
+	c.lastLatency = c.windowLatency / time.Duration(c.windowCount)
+	if c.adaptive {
+		c.adaptNeedLock()
+	}
+
+	c.windowStart = now
+	c.windowCount = 0
+	c.windowLatency = 0
+	c.windowOverloads = 0
+	c.windowBusy = c.active >= c.limit
+}
+
+func (c *throughputController) adaptNeedLock() {
+	oldLimit := c.limit
+	switch {
+	case c.windowOverloads > 0:
+		c.limit = max(1, min(c.limit-1, c.limit*3/4))
+	case c.lastLatency > c.targetLatency:
+		c.limit = max(1, c.limit-1)
+	case c.windowBusy:
+		c.limit = min(c.maxLimit, c.limit+1)
+	}
+
+	if c.limit != oldLimit {
+		if c.limit > oldLimit {
+			c.cond.Broadcast()
+		}
+		if checkPgQueriesConfig.printErrorsInProgress {
+			log.Printf("Parallel checks changed %v -> %v, latency: %v, overload errors: %v", oldLimit, c.limit, c.lastLatency, c.windowOverloads)
+		}
+	}
+}
+
+// String describe current throughput for progress output
+func (c *throughputController) String() string {
+	if c == nil {
+		return ""
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return fmt.Sprintf("parallel: %v/%v, qps: %.1f, latency: %v", c.limit, c.maxLimit, c.lastQps, c.lastLatency.Round(time.Millisecond))
+}
+
+// tokenBucket allow rate events per second with burst of one second
+type tokenBucket struct {
+	m      sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := max(1, rate)
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+	}
+}
+
+// reserve take one token and return delay until the token will be available
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
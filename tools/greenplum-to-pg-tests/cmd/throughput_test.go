@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(2)
+	start := time.Unix(0, 0)
+
+	require.Zero(t, bucket.reserve(start))
+	require.Zero(t, bucket.reserve(start))
+	require.Equal(t, 500*time.Millisecond, bucket.reserve(start))
+	require.Equal(t, time.Second, bucket.reserve(start))
+
+	// tokens refilled, but not more than burst
+	require.Zero(t, bucket.reserve(start.Add(10*time.Second)))
+	require.Zero(t, bucket.reserve(start.Add(10*time.Second)))
+	require.Equal(t, 500*time.Millisecond, bucket.reserve(start.Add(10*time.Second)))
+}
+
+func TestAdaptiveParallel(t *testing.T) {
+	now := time.Unix(0, 0)
+	controller := newThroughputController(0, 2, true, 4, 100*time.Millisecond)
+	controller.now = func() time.Time { return now }
+	controller.windowStart = now
+	require.Equal(t, 4, controller.Workers())
+
+	observeWindow := func(latency time.Duration, overloaded bool) {
+		controller.Observe(latency, false)
+		now = now.Add(adaptInterval)
+		controller.Observe(latency, overloaded)
+	}
+
+	// busy checkers with low latency - grow
+	controller.Acquire()
+	controller.Acquire()
+	observeWindow(10*time.Millisecond, false)
+	require.Equal(t, 3, controller.limit)
+	controller.Acquire()
+	observeWindow(10*time.Millisecond, false)
+	require.Equal(t, 4, controller.limit)
+	controller.Acquire()
+	observeWindow(10*time.Millisecond, false)
+	require.Equal(t, 4, controller.limit, "max limit")
+	require.Equal(t, "parallel: 4/4, qps: 2.0, latency: 10ms", controller.String())
+
+	// high latency - shrink by one, overload - shrink faster
+	observeWindow(time.Second, false)
+	require.Equal(t, 3, controller.limit)
+	observeWindow(10*time.Millisecond, true)
+	require.Equal(t, 2, controller.limit)
+	observeWindow(10*time.Millisecond, true)
+	require.Equal(t, 1, controller.limit)
+	observeWindow(10*time.Millisecond, true)
+	require.Equal(t, 1, controller.limit, "min limit")
+
+	// not busy checkers - limit stay same, first window after release started busy
+	for range 4 {
+		controller.Release()
+	}
+	observeWindow(10*time.Millisecond, false)
+	require.Equal(t, 2, controller.limit)
+	observeWindow(10*time.Millisecond, false)
+	observeWindow(10*time.Millisecond, false)
+	require.Equal(t, 2, controller.limit)
+}
+
+func TestFixedParallel(t *testing.T) {
+	controller := newThroughputController(0, 3, false, 50, time.Second)
+	require.Equal(t, 3, controller.Workers())
+
+	var nilController *throughputController
+	nilController.Acquire()
+	nilController.Release()
+	nilController.Observe(time.Second, true)
+	require.Empty(t, nilController.String())
+}
+
+// testSessionReplayer replay every transaction successfully with fixed latency
+type testSessionReplayer struct {
+	queries int
+}
+
+func (r *testSessionReplayer) ReplaySession(ctx context.Context, session *internal.Session, beforeQuery func(ctx context.Context)) []error {
+	session.Success = true
+	for i := range session.Transactions {
+		for range session.Transactions[i].Queries {
+			beforeQuery(ctx)
+			r.queries++
+		}
+		session.Transactions[i].Success = true
+		session.Transactions[i].Latency = 200 * time.Millisecond
+	}
+	return make([]error, len(session.Transactions))
+}
+
+func TestReplayThroughput(t *testing.T) {
+	now := time.Unix(0, 0)
+	controller := newThroughputController(1000, 2, false, 2, time.Second)
+	controller.now = func() time.Time { return now }
+	controller.windowStart = now
+	checkThroughput = controller
+	t.Cleanup(func() { checkThroughput = nil })
+
+	session := internal.Session{Transactions: []internal.Transaction{
+		{LogSuccess: true, Queries: []internal.Query{{Text: "SELECT 1"}, {Text: "SELECT 2"}}},
+		{LogSuccess: true, Queries: []internal.Query{{Text: "SELECT 3"}}},
+	}}
+	var replayer testSessionReplayer
+	var stats QueryStats
+	replaySession(Rules{}, &stats, &replayer, &session)
+
+	require.Equal(t, 3, replayer.queries)
+	// transactions counted separately from queries
+	require.Equal(t, 0, stats.GetTotalCount())
+	require.Equal(t, 2, stats.transactionsCount)
+	require.Equal(t, 2, stats.transactionsOkCount)
+	require.Equal(t, 1, stats.sessionsOkCount)
+	// token taken for every query
+	require.InDelta(t, 997, controller.bucket.tokens, 0.001)
+	require.Equal(t, 2, controller.windowCount)
+	require.Equal(t, 400*time.Millisecond, controller.windowLatency)
+}
+
+// testFailedSessionReplayer fail every transaction with the error
+type testFailedSessionReplayer struct {
+	err error
+}
+
+func (r testFailedSessionReplayer) ReplaySession(ctx context.Context, session *internal.Session, beforeQuery func(ctx context.Context)) []error {
+	txErrors := make([]error, len(session.Transactions))
+	for i := range txErrors {
+		txErrors[i] = r.err
+	}
+	return txErrors
+}
+
+func TestReplayTransientSession(t *testing.T) {
+	newSession := func() internal.Session {
+		return internal.Session{Transactions: []internal.Transaction{
+			{LogSuccess: true, Queries: []internal.Query{{Text: "CREATE TABLE t (a int) DISTRIBUTED RANDOMLY"}}},
+		}}
+	}
+
+	var stats QueryStats
+	session := newSession()
+	replaySession(Rules{}, &stats, testFailedSessionReplayer{err: context.DeadlineExceeded}, &session)
+	require.Equal(t, 0, stats.sessionsCount, "transient session excluded from total")
+	require.Equal(t, 1, stats.sessionsTransient)
+	require.Equal(t, 1, stats.transientCount)
+	require.Empty(t, stats.Rewrites)
+
+	session = newSession()
+	replaySession(Rules{}, &stats, testFailedSessionReplayer{err: errors.New("syntax error")}, &session)
+	require.Equal(t, 1, stats.sessionsCount)
+	require.Equal(t, 0, stats.sessionsOkCount)
+	require.Equal(t, 1, stats.sessionsTransient)
+	require.NotEmpty(t, stats.Rewrites, "rewrites counted for transactions with verdict")
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ydb-platform/postgres-compatibility-tests/tools/greenplum-to-pg-tests/internal"
+)
+
+// verdictCache store check outcomes by query fingerprint for skip check duplicated queries.
+// Outcomes may be persisted to append-only file and reused by next runs with same server version.
+type verdictCache struct {
+	m        sync.Mutex
+	outcomes map[string]checkOutcome
+	seen     map[string]bool // fingerprints, seen in current run
+
+	version string
+	file    *os.File
+	encoder *json.Encoder
+}
+
+type storedVerdict struct {
+	Version     string `json:"version"`
+	Fingerprint string `json:"fingerprint"`
+	checkOutcome
+}
+
+// verdictCacheKey return fingerprint of the query with types of its placeholders,
+// because same query may pass or fail depending on inferred types after schema change
+func verdictCacheKey(queryText string, paramTypes []string) string {
+	fingerprint := internal.QueryFingerprint(queryText)
+	if len(paramTypes) == 0 {
+		return fingerprint
+	}
+	return fingerprint + " -- param types: " + strings.Join(paramTypes, ", ")
+}
+
+func newVerdictCache() *verdictCache {
+	return &verdictCache{
+		outcomes: make(map[string]checkOutcome),
+		seen:     make(map[string]bool),
+	}
+}
+
+func openVerdictCache(path string, version string) (*verdictCache, error) {
+	res := newVerdictCache()
+	res.version = version
+
+	if err := res.load(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open verdict cache file %q for append: %w", path, err)
+	}
+	res.file = f
+	res.encoder = json.NewEncoder(f)
+	return res, nil
+}
+
+func (c *verdictCache) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open verdict cache file %q: %w", path, err)
+	}
+	defer f.Close()
+
+	const maxLineSize = 64 * 1024 * 1024
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLineSize)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		var item storedVerdict
+		if err = json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			// last line may be broken if previous run was killed
+			log.Printf("Skip broken line %v of verdict cache: %v", lineNum, err)
+			continue
+		}
+		if item.Version == c.version {
+			c.outcomes[item.Fingerprint] = item.checkOutcome
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read verdict cache file %q: %w", path, err)
+	}
+
+	log.Printf("Loaded verdicts from cache: %v", len(c.outcomes))
+	return nil
+}
+
+func (c *verdictCache) Get(fingerprint string) (checkOutcome, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	outcome, ok := c.outcomes[fingerprint]
+	return outcome, ok
+}
+
+// Put save the outcome if the fingerprint is new, the fingerprint may be checked in parallel
+func (c *verdictCache) Put(fingerprint string, outcome checkOutcome) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, ok := c.outcomes[fingerprint]; ok {
+		return
+	}
+	c.outcomes[fingerprint] = outcome
+
+	if c.encoder != nil {
+		err := c.encoder.Encode(storedVerdict{
+			Version:      c.version,
+			Fingerprint:  fingerprint,
+			checkOutcome: outcome,
+		})
+		if err != nil {
+			log.Printf("Failed to write verdict to cache file: %v", err)
+		}
+	}
+}
+
+// MarkSeen return true if the fingerprint seen first time in current run.
+// Seen fingerprints doesn't store between runs, so distinct counters are approximate after resume.
+func (c *verdictCache) MarkSeen(fingerprint string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.seen[fingerprint] {
+		return false
+	}
+	c.seen[fingerprint] = true
+	return true
+}
+
+func (c *verdictCache) Close() error {
+	if c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
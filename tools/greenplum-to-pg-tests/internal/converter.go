@@ -55,6 +55,43 @@ const (
 	ObjectTypeView
 )
 
+func (t objectType) String() string {
+	switch t {
+	case ObjectTypeTable:
+		return "TABLE"
+	case ObjectTypeView:
+		return "VIEW"
+	default:
+		return "NONE"
+	}
+}
+
+// SchemaObject is converted object from schema dump, ready for create
+type SchemaObject struct {
+	Schema        string
+	Name          string
+	ConvertedName string // identifier for queries, quoted if needed
+	Type          objectType
+	SQL           string
+}
+
+// Objects return converted objects of the type, sorted by schema and name
+func (c *PgSchema) Objects(t objectType) []SchemaObject {
+	var res []SchemaObject
+	for _, schema := range extractKeys(c.creations) {
+		for _, name := range extractKeys(c.creations[schema][t]) {
+			res = append(res, SchemaObject{
+				Schema:        schema,
+				Name:          name,
+				ConvertedName: QuoteIdentifier(ConvertedName(identifierTextValue(schema), identifierTextValue(name))),
+				Type:          t,
+				SQL:           c.creations[schema][t][name],
+			})
+		}
+	}
+	return res
+}
+
 func (c *PgSchema) Read(reader io.Reader) {
 	c.input = bufio.NewScanner(reader)
 	c.parseInput()
@@ -219,7 +256,7 @@ func (c *PgSchema) ensureWriteString(s string) {
 		if len(s) > maxLineLen {
 			s = s[:maxLineLen] + "..."
 		}
-		log.Fatalf("Failed to write string %q: %+v", err)
+		log.Fatalf("Failed to write string %q: %+v", s, err)
 	}
 }
 
@@ -235,11 +272,36 @@ func extractNames(line string) (schemaName, tableName string) {
 	return schemaName, tableName
 }
 
+// replaceSchemaAndName replace qualified name schemaName.name by converted name, the name compared as identifier,
+// so quoting of the parts may differ. Strings and comments of the text stay unchanged.
 func replaceSchemaAndName(text, schemaName, name string) string {
-	from := schemaName + "." + name
+	schemaValue := identifierTextValue(schemaName)
+	nameValue := identifierTextValue(name)
+	to := QuoteIdentifier(ConvertedName(schemaValue, nameValue))
+
+	return ReplaceQualifiedNames(text, func(tokens []Token, qualifiedName QualifiedName) (string, bool) {
+		if len(qualifiedName.Parts) != 2 ||
+			IdentifierValue(tokens[qualifiedName.Parts[0]]) != schemaValue ||
+			IdentifierValue(tokens[qualifiedName.Parts[1]]) != nameValue {
+			return "", false
+		}
+		return to, true
+	})
+}
 
-	unquotedName := strings.Trim(name, "\"")
-	to := schemaName + "___" + unquotedName
+// identifierTextValue return value of identifier from text of one identifier token
+func identifierTextValue(text string) string {
+	tokens := LexSQL(text)
+	if len(tokens) != 1 {
+		return text
+	}
+	return IdentifierValue(tokens[0])
+}
+
+// ConvertedName return name of object in db without schemas support. Schema and name are identifier values
+// (see IdentifierValue), result is value too: use QuoteIdentifier for put it to query.
+func ConvertedName(schemaName, name string) string {
+	to := schemaName + "___" + name
 
 	const maxPgNameLen = 63
 	const hashLen = 8
@@ -251,9 +313,7 @@ func replaceSchemaAndName(text, schemaName, name string) string {
 		to = to[:origNameLen] + hashString[:hashLen]
 	}
 
-	res := strings.Replace(text, from, to, -1)
-
-	return res
+	return to
 }
 
 func extractKeys[K ordered, V any](m map[K]V) []K {
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"strings"
+)
+
+const (
+	fingerprintString = "?str"
+	fingerprintNumber = "?num"
+	fingerprintList   = "..."
+)
+
+// QueryFingerprint normalize query same way as pg_stat_statements: literals replaced by placeholder
+// with kind of the literal, lists of literals in IN replaced by one placeholder, comments removed and whitespaces collapsed.
+// Kind of literals is kept, because string and number literals may have different check results.
+// Queries with same fingerprint has same shape and expected to have same check result.
+func QueryFingerprint(queryText string) string {
+	tokens := normalizeLiterals(LexSQL(queryText))
+	tokens = collapseInLists(tokens)
+
+	buf := &strings.Builder{}
+	needSpace := false
+	for _, token := range tokens {
+		if !token.IsMeaningful() {
+			needSpace = buf.Len() > 0
+			continue
+		}
+		if needSpace {
+			buf.WriteByte(' ')
+			needSpace = false
+		}
+		buf.WriteString(token.Text)
+	}
+	return buf.String()
+}
+
+func normalizeLiterals(tokens []Token) []Token {
+	res := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		switch token.Kind {
+		case TokenString:
+			res = append(res, Token{Kind: token.Kind, Text: fingerprintString})
+		case TokenNumber:
+			res = append(res, Token{Kind: token.Kind, Text: fingerprintNumber})
+		default:
+			res = append(res, token)
+		}
+	}
+	return res
+}
+
+// collapseInLists replace IN (?, ?, ...) by IN (...), the list keep kind of literals if all of them have same kind:
+// IN (...str) or IN (...num)
+func collapseInLists(tokens []Token) []Token {
+	res := make([]Token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		res = append(res, tokens[i])
+		if !tokens[i].IsKeyword("IN") {
+			continue
+		}
+
+		open := nextMeaningful(tokens, i+1)
+		if open < 0 || tokens[open].Text != "(" {
+			continue
+		}
+
+		closePos := -1
+		kinds := map[TokenKind]bool{}
+		for j := open + 1; j < len(tokens); j++ {
+			token := tokens[j]
+			if !token.IsMeaningful() || token.Text == "," {
+				continue
+			}
+			if token.Kind == TokenParam || token.Kind == TokenString || token.Kind == TokenNumber {
+				kinds[token.Kind] = true
+				continue
+			}
+			if token.Text == ")" {
+				closePos = j
+			}
+			break
+		}
+		if closePos < 0 || nextMeaningful(tokens, open+1) == closePos {
+			continue
+		}
+
+		list := fingerprintList
+		switch {
+		case len(kinds) == 1 && kinds[TokenString]:
+			list += fingerprintString[1:]
+		case len(kinds) == 1 && kinds[TokenNumber]:
+			list += fingerprintNumber[1:]
+		}
+		res = append(res,
+			Token{Kind: TokenWhitespace, Text: " "},
+			Token{Kind: TokenPunctuation, Text: "("},
+			Token{Kind: TokenString, Text: list},
+			Token{Kind: TokenPunctuation, Text: ")"},
+		)
+		i = closePos
+	}
+	return res
+}
+
+func nextMeaningful(tokens []Token, from int) int {
+	for i := from; i < len(tokens); i++ {
+		if tokens[i].IsMeaningful() {
+			return i
+		}
+	}
+	return -1
+}
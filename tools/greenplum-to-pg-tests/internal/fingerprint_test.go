@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexSQL(t *testing.T) {
+	table := []struct {
+		name   string
+		query  string
+		tokens []Token
+	}{
+		{
+			name:  "Simple",
+			query: "SELECT a::int FROM t",
+			tokens: []Token{
+				{TokenIdentifier, "SELECT"}, {TokenWhitespace, " "}, {TokenIdentifier, "a"},
+				{TokenOperator, "::"}, {TokenIdentifier, "int"}, {TokenWhitespace, " "},
+				{TokenIdentifier, "FROM"}, {TokenWhitespace, " "}, {TokenIdentifier, "t"},
+			},
+		},
+		{
+			name:  "Strings",
+			query: `'it''s' E'a\'b' $$x'y$$ $t$a$$b$t$`,
+			tokens: []Token{
+				{TokenString, `'it''s'`}, {TokenWhitespace, " "}, {TokenString, `E'a\'b'`}, {TokenWhitespace, " "},
+				{TokenString, `$$x'y$$`}, {TokenWhitespace, " "}, {TokenString, `$t$a$$b$t$`},
+			},
+		},
+		{
+			name:  "Comments",
+			query: "1 -- a 'b'\n/* c /* d */ e */\"Q\"\"x\"",
+			tokens: []Token{
+				{TokenNumber, "1"}, {TokenWhitespace, " "}, {TokenComment, "-- a 'b'"}, {TokenWhitespace, "\n"},
+				{TokenComment, "/* c /* d */ e */"}, {TokenQuotedIdentifier, `"Q""x"`},
+			},
+		},
+		{
+			name:  "Params",
+			query: "a=$1 AND b>=-1.5e3",
+			tokens: []Token{
+				{TokenIdentifier, "a"}, {TokenOperator, "="}, {TokenParam, "$1"}, {TokenWhitespace, " "},
+				{TokenIdentifier, "AND"}, {TokenWhitespace, " "}, {TokenIdentifier, "b"}, {TokenOperator, ">="},
+				{TokenOperator, "-"}, {TokenNumber, "1.5e3"},
+			},
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			tokens := LexSQL(test.query)
+			require.Equal(t, test.tokens, tokens)
+
+			var texts []string
+			for _, token := range tokens {
+				texts = append(texts, token.Text)
+			}
+			require.Equal(t, test.query, strings.Join(texts, ""))
+		})
+	}
+}
+
+func TestQueryFingerprint(t *testing.T) {
+	table := []struct {
+		query       string
+		fingerprint string
+	}{
+		{
+			query:       "SELECT * FROM t WHERE id = 12",
+			fingerprint: "SELECT * FROM t WHERE id = ?num",
+		},
+		{
+			query:       "SELECT * FROM t WHERE id = '12'",
+			fingerprint: "SELECT * FROM t WHERE id = ?str",
+		},
+		{
+			query:       "SELECT *\n  FROM t -- comment\n WHERE name='x' AND id IN (1, 2,3)",
+			fingerprint: "SELECT * FROM t WHERE name=?str AND id IN (...num)",
+		},
+		{
+			query:       "SELECT * FROM t WHERE id IN ('1', '2') OR id IN (1, '2')",
+			fingerprint: "SELECT * FROM t WHERE id IN (...str) OR id IN (...)",
+		},
+		{
+			query:       "select * from t where id in ($1, $2) and a in (select b from c)",
+			fingerprint: "select * from t where id in (...) and a in (select b from c)",
+		},
+		{
+			query:       "INSERT INTO t VALUES ($1, 'a', $$b$$)",
+			fingerprint: "INSERT INTO t VALUES ($1, ?str, ?str)",
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.query, func(t *testing.T) {
+			require.Equal(t, test.fingerprint, QueryFingerprint(test.query))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+type issuePartKind int
+
+const (
+	issuePartText issuePartKind = iota
+	issuePartNumber
+	issuePartQuoted
+	issuePartBracketed
+)
+
+type issuePart struct {
+	kind issuePartKind
+	text string
+}
+
+// splitIssueMessage split message of issue to fixed text and variable parts:
+// numbers, quoted strings and identifiers, bracketed ydb paths
+func splitIssueMessage(message string) []issuePart {
+	var res []issuePart
+	addText := func(text string) {
+		if len(res) > 0 && res[len(res)-1].kind == issuePartText {
+			res[len(res)-1].text += text
+			return
+		}
+		res = append(res, issuePart{kind: issuePartText, text: text})
+	}
+
+	for i := 0; i < len(message); {
+		c := message[i]
+		switch {
+		case (c == '\'' || c == '"' || c == '`') && (i == 0 || !isIdentifierStart(rune(message[i-1]))):
+			end := strings.IndexByte(message[i+1:], c)
+			if end < 0 {
+				addText(message[i : i+1])
+				i++
+				continue
+			}
+			res = append(res, issuePart{kind: issuePartQuoted, text: message[i : i+end+2]})
+			i += end + 2
+		case c == '[':
+			end := strings.IndexByte(message[i+1:], ']')
+			if end < 0 {
+				addText(message[i : i+1])
+				i++
+				continue
+			}
+			res = append(res, issuePart{kind: issuePartBracketed, text: message[i : i+end+2]})
+			i += end + 2
+		case isDigit(rune(c)) && (i == 0 || !isIdentifierStart(rune(message[i-1])) && !isDigit(rune(message[i-1]))):
+			end := i
+			for end < len(message) && isDigit(rune(message[end])) {
+				end++
+			}
+			if end < len(message) && isIdentifierStart(rune(message[end])) {
+				// part of word like 3rd or 1st
+				addText(message[i:end])
+			} else {
+				res = append(res, issuePart{kind: issuePartNumber, text: message[i:end]})
+			}
+			i = end
+		default:
+			addText(message[i : i+1])
+			i++
+		}
+	}
+	return res
+}
+
+// NormalizeIssueMessage replace numbers, quoted strings and identifiers and bracketed paths in issue message
+// by placeholders, for group same problems with different tables, values or positions
+func NormalizeIssueMessage(message string) string {
+	buf := &strings.Builder{}
+	for _, part := range splitIssueMessage(message) {
+		switch part.kind {
+		case issuePartText:
+			buf.WriteString(part.text)
+		case issuePartNumber:
+			buf.WriteString("?")
+		case issuePartQuoted:
+			buf.WriteByte(part.text[0])
+			buf.WriteString("?")
+			buf.WriteByte(part.text[0])
+		case issuePartBracketed:
+			buf.WriteString("[?]")
+		}
+	}
+	return buf.String()
+}
+
+// numberPlaceholderRegexp match number or ? placeholder of normalized message
+const numberPlaceholderRegexp = `(?:\d+|\?)`
+
+// IssueMessageRegexp return regexp, which match the message and all messages with same normalized form.
+// Message may be normalized already, standalone ? (not a part of word) is number placeholder.
+func IssueMessageRegexp(message string) string {
+	buf := &strings.Builder{}
+	buf.WriteString("^")
+	for _, part := range splitIssueMessage(message) {
+		switch part.kind {
+		case issuePartText:
+			writeIssueTextRegexp(buf, part.text)
+		case issuePartNumber:
+			buf.WriteString(numberPlaceholderRegexp)
+		case issuePartQuoted:
+			quote := regexp.QuoteMeta(part.text[:1])
+			buf.WriteString(quote + "[^" + quote + "]*" + quote)
+		case issuePartBracketed:
+			buf.WriteString(`\[[^\]]*\]`)
+		}
+	}
+	buf.WriteString("$")
+	return buf.String()
+}
+
+// writeIssueTextRegexp write quoted text, standalone ? replaced by number placeholder and ? in words kept as is
+func writeIssueTextRegexp(buf *strings.Builder, text string) {
+	isWordByte := func(i int) bool {
+		return i >= 0 && i < len(text) && (isIdentifierStart(rune(text[i])) || isDigit(rune(text[i])))
+	}
+
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != '?' || isWordByte(i-1) || isWordByte(i+1) {
+			continue
+		}
+		buf.WriteString(regexp.QuoteMeta(text[start:i]))
+		buf.WriteString(numberPlaceholderRegexp)
+		start = i + 1
+	}
+	buf.WriteString(regexp.QuoteMeta(text[start:]))
+}
@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeIssueMessage(t *testing.T) {
+	table := []struct {
+		messages   []string
+		normalized string
+	}{
+		{
+			messages: []string{
+				"Cannot find table 'db.[/local/orders]' because it does not exist or you do not have access permissions.",
+				"Cannot find table 'db.[/local/users_2]' because it does not exist or you do not have access permissions.",
+			},
+			normalized: "Cannot find table '?' because it does not exist or you do not have access permissions.",
+		},
+		{
+			messages: []string{
+				"<main>:1:15: Error: Column \"a\" doesn't exist",
+				"<main>:12:3: Error: Column \"other\" doesn't exist",
+			},
+			normalized: "<main>:?:?: Error: Column \"?\" doesn't exist",
+		},
+		{
+			messages:   []string{"alternative is not implemented yet : 37"},
+			normalized: "alternative is not implemented yet : ?",
+		},
+		{
+			messages:   []string{"Unknown type [pg_catalog.int8] for table t1"},
+			normalized: "Unknown type [?] for table t1",
+		},
+		{
+			messages:   []string{"operator does not exist: ? = text?", "operator does not exist: 5 = text?"},
+			normalized: "operator does not exist: ? = text?",
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.normalized, func(t *testing.T) {
+			require.True(t, regexp.MustCompile(IssueMessageRegexp(test.normalized)).MatchString(test.normalized))
+			expr := regexp.MustCompile(IssueMessageRegexp(test.normalized))
+			for _, message := range test.messages {
+				require.Equal(t, test.normalized, NormalizeIssueMessage(message))
+				require.Equal(t, IssueMessageRegexp(test.normalized), IssueMessageRegexp(message))
+				require.True(t, expr.MatchString(message), message)
+			}
+		})
+	}
+}
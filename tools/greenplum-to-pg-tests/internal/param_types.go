@@ -0,0 +1,500 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+)
+
+// UnknownParamType is type of $N placeholder, which can't be inferred. Server infer it by itself.
+const UnknownParamType = "unknown"
+
+// ColumnTypes is types of table columns, normalized to postgres internal type names
+type ColumnTypes map[string]map[string]string // [table name][column name] type
+
+// pgParamType describe type for pass typed parameters to server
+type pgParamType struct {
+	oid    uint32
+	sample string // valid text value of the type, used for explain queries
+}
+
+// pgParamTypes contains supported types by internal postgres names, array types has prefix _
+var pgParamTypes = map[string]pgParamType{
+	"bool":         {oid: 16, sample: "false"},
+	"bytea":        {oid: 17, sample: `\x`},
+	"name":         {oid: 19, sample: ""},
+	"int8":         {oid: 20, sample: "0"},
+	"int2":         {oid: 21, sample: "0"},
+	"int4":         {oid: 23, sample: "0"},
+	"text":         {oid: 25, sample: ""},
+	"oid":          {oid: 26, sample: "0"},
+	"json":         {oid: 114, sample: "null"},
+	"cidr":         {oid: 650, sample: "127.0.0.1/32"},
+	"float4":       {oid: 700, sample: "0"},
+	"float8":       {oid: 701, sample: "0"},
+	"unknown":      {oid: 705, sample: ""},
+	"inet":         {oid: 869, sample: "127.0.0.1"},
+	"_bool":        {oid: 1000, sample: "{}"},
+	"_int2":        {oid: 1005, sample: "{}"},
+	"_int4":        {oid: 1007, sample: "{}"},
+	"_text":        {oid: 1009, sample: "{}"},
+	"_varchar":     {oid: 1015, sample: "{}"},
+	"_int8":        {oid: 1016, sample: "{}"},
+	"_float8":      {oid: 1022, sample: "{}"},
+	"bpchar":       {oid: 1042, sample: ""},
+	"varchar":      {oid: 1043, sample: ""},
+	"date":         {oid: 1082, sample: "2000-01-01"},
+	"time":         {oid: 1083, sample: "00:00:00"},
+	"timestamp":    {oid: 1114, sample: "2000-01-01 00:00:00"},
+	"_timestamp":   {oid: 1115, sample: "{}"},
+	"_date":        {oid: 1182, sample: "{}"},
+	"timestamptz":  {oid: 1184, sample: "2000-01-01 00:00:00+00"},
+	"_timestamptz": {oid: 1185, sample: "{}"},
+	"interval":     {oid: 1186, sample: "0"},
+	"_numeric":     {oid: 1231, sample: "{}"},
+	"timetz":       {oid: 1266, sample: "00:00:00+00"},
+	"numeric":      {oid: 1700, sample: "0"},
+	"uuid":         {oid: 2950, sample: "00000000-0000-0000-0000-000000000000"},
+	"_uuid":        {oid: 2951, sample: "{}"},
+	"jsonb":        {oid: 3802, sample: "null"},
+}
+
+// typeAliases map sql names of types to internal postgres names
+var typeAliases = map[string]string{
+	"bigint":                      "int8",
+	"bigserial":                   "int8",
+	"serial8":                     "int8",
+	"boolean":                     "bool",
+	"char":                        "bpchar",
+	"character":                   "bpchar",
+	"character varying":           "varchar",
+	"char varying":                "varchar",
+	"decimal":                     "numeric",
+	"double precision":            "float8",
+	"float":                       "float8",
+	"int":                         "int4",
+	"integer":                     "int4",
+	"real":                        "float4",
+	"serial":                      "int4",
+	"serial4":                     "int4",
+	"smallint":                    "int2",
+	"smallserial":                 "int2",
+	"serial2":                     "int2",
+	"time without time zone":      "time",
+	"time with time zone":         "timetz",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+}
+
+// PgParamType return oid of the type and valid value of the type for pass typed parameter to server.
+// Unsupported types are passed as unknown.
+func PgParamType(typeName string) (oid uint32, sample string) {
+	t, ok := pgParamTypes[typeName]
+	if !ok {
+		t = pgParamTypes[UnknownParamType]
+	}
+	return t.oid, t.sample
+}
+
+// normalizeTypeName return internal postgres name of the type or UnknownParamType for unsupported types
+func normalizeTypeName(name string, isArray bool) string {
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+	if isArray {
+		name = "_" + name
+	}
+	if _, ok := pgParamTypes[name]; !ok || name == UnknownParamType {
+		return UnknownParamType
+	}
+	return name
+}
+
+// ColumnTypes parse column types of tables. Tables indexed by converted name and by name without schema,
+// if the name is unique over schemas.
+func (c *PgSchema) ColumnTypes() ColumnTypes {
+	res := ColumnTypes{}
+	shortNames := map[string]int{}
+	for _, table := range c.Objects(ObjectTypeTable) {
+		columns := parseColumnTypes(table.SQL)
+		res[identifierTextValue(table.ConvertedName)] = columns
+
+		shortName := identifierTextValue(table.Name)
+		shortNames[shortName]++
+		if shortNames[shortName] == 1 {
+			res[shortName] = columns
+		} else {
+			delete(res, shortName)
+		}
+	}
+	return res
+}
+
+// parseColumnTypes parse column definitions from converted create table, one column per line
+func parseColumnTypes(createTable string) map[string]string {
+	res := map[string]string{}
+	lines := strings.Split(createTable, "\n")
+	for _, line := range lines[1:] {
+		tokens := meaningfulTokens(LexSQL(line))
+		if len(tokens) < 2 || !isIdentifierToken(tokens[0]) || isTableConstraint(tokens[0]) {
+			continue
+		}
+		if typeName := parseTypeName(tokens, 1); typeName != UnknownParamType {
+			res[IdentifierValue(tokens[0])] = typeName
+		}
+	}
+	return res
+}
+
+func isTableConstraint(token Token) bool {
+	for _, keyword := range []string{"CHECK", "CONSTRAINT", "EXCLUDE", "FOREIGN", "LIKE", "PRIMARY", "UNIQUE"} {
+		if token.IsKeyword(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTypeName parse type name, started from tokens[start], like character varying(10)[] or pg_catalog.int4.
+// Return normalized type.
+func parseTypeName(tokens []Token, start int) string {
+	pos := start
+	if pos+2 < len(tokens) && isIdentifierToken(tokens[pos]) && tokens[pos+1].Text == "." {
+		pos += 2 // schema of the type
+	}
+	if pos >= len(tokens) || !isIdentifierToken(tokens[pos]) {
+		return UnknownParamType
+	}
+
+	words := []string{IdentifierValue(tokens[pos])}
+	pos++
+	isKeyword := func(keyword string) bool {
+		return pos < len(tokens) && tokens[pos].IsKeyword(keyword)
+	}
+	switch words[0] {
+	case "double":
+		if isKeyword("PRECISION") {
+			words = append(words, "precision")
+			pos++
+		}
+	case "character", "char":
+		if isKeyword("VARYING") {
+			words = append(words, "varying")
+			pos++
+		}
+	case "time", "timestamp":
+		pos = skipParentheses(tokens, pos)
+		if (isKeyword("WITH") || isKeyword("WITHOUT")) && pos+2 < len(tokens) &&
+			tokens[pos+1].IsKeyword("TIME") && tokens[pos+2].IsKeyword("ZONE") {
+			words = append(words, strings.ToLower(tokens[pos].Text), "time", "zone")
+			pos += 3
+		}
+	}
+	pos = skipParentheses(tokens, pos)
+
+	isArray := pos < len(tokens) && tokens[pos].Text == "["
+	return normalizeTypeName(strings.Join(words, " "), isArray)
+}
+
+// skipParentheses return index of token after balanced parentheses, started from tokens[pos], or pos if there are no parentheses
+func skipParentheses(tokens []Token, pos int) int {
+	if pos >= len(tokens) || tokens[pos].Text != "(" {
+		return pos
+	}
+	depth := 0
+	for i := pos; i < len(tokens); i++ {
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(tokens)
+}
+
+func meaningfulTokens(tokens []Token) []Token {
+	res := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.IsMeaningful() {
+			res = append(res, token)
+		}
+	}
+	return res
+}
+
+// InferParamTypes return types of placeholders $1..$N of the query, nil if the query has no placeholders.
+// Types are taken from casts, columns of tables from the query compared or inserted to the placeholders
+// and LIMIT/OFFSET clauses. Types, which can't be inferred, are UnknownParamType.
+func InferParamTypes(queryText string, columns ColumnTypes) []string {
+	tokens := meaningfulTokens(LexSQL(queryText))
+	var res []string
+	for _, token := range tokens {
+		if n := paramNumber(token); n > len(res) {
+			res = append(res, make([]string, n-len(res))...)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+
+	inference := paramInference{tokens: tokens, columns: columns, tables: referencedTables(tokens, columns)}
+	set := func(n int, typeName string) {
+		if res[n-1] == "" && typeName != UnknownParamType {
+			res[n-1] = typeName
+		}
+	}
+
+	// explicit casts are most reliable, then columns of insert and then other contexts
+	for i, token := range tokens {
+		if n := paramNumber(token); n > 0 {
+			set(n, inference.castType(i))
+		}
+	}
+	for n, typeName := range inference.insertTypes() {
+		set(n, typeName)
+	}
+	for i, token := range tokens {
+		if n := paramNumber(token); n > 0 {
+			set(n, inference.contextType(i))
+		}
+	}
+
+	for i := range res {
+		if res[i] == "" {
+			res[i] = UnknownParamType
+		}
+	}
+	return res
+}
+
+// maxParamNumber is limit of placeholders count in postgres protocol
+const maxParamNumber = 65535
+
+// paramNumber return N for $N token or 0, numbers above maxParamNumber aren't valid placeholders
+func paramNumber(token Token) int {
+	if token.Kind != TokenParam {
+		return 0
+	}
+	n, err := strconv.Atoi(token.Text[1:])
+	if err != nil || n < 1 || n > maxParamNumber {
+		return 0
+	}
+	return n
+}
+
+// referencedTables return tables from the columns, which names are used in the query
+func referencedTables(tokens []Token, columns ColumnTypes) []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, token := range tokens {
+		if !isIdentifierToken(token) {
+			continue
+		}
+		name := IdentifierValue(token)
+		if _, ok := columns[name]; ok && !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// paramInference infer type of placeholder by meaningful tokens around it
+type paramInference struct {
+	tokens  []Token
+	columns ColumnTypes
+	tables  []string
+}
+
+var comparisonOperators = []string{"=", "<>", "!=", "<", ">", "<=", ">="}
+
+// castType return type from $N::type or CAST($N AS type)
+func (p paramInference) castType(pos int) string {
+	switch {
+	case p.is(pos+1, "::"):
+		return parseTypeName(p.tokens, pos+2)
+	case p.is(pos-1, "(") && p.isKeyword(pos-2, "CAST") && p.isKeyword(pos+1, "AS"):
+		return parseTypeName(p.tokens, pos+2)
+	default:
+		return UnknownParamType
+	}
+}
+
+// contextType return type from comparison with column, IN list, BETWEEN, ANY, LIKE or LIMIT/OFFSET
+func (p paramInference) contextType(pos int) string {
+	switch {
+	case p.isKeyword(pos-1, "LIMIT") || p.isKeyword(pos-1, "OFFSET"):
+		return "int8"
+	case p.isComparison(pos - 1):
+		return p.columnTypeBefore(pos - 2)
+	case p.isComparison(pos + 1):
+		return p.columnTypeAfter(pos + 2)
+	case p.isKeyword(pos-1, "LIKE") || p.isKeyword(pos-1, "ILIKE"):
+		return "text"
+	case p.isKeyword(pos-1, "BETWEEN"):
+		return p.columnTypeBefore(pos - 2)
+	case p.isKeyword(pos-1, "AND") && p.isParam(pos-2) && p.isKeyword(pos-3, "BETWEEN"):
+		return p.columnTypeBefore(pos - 4)
+	case p.is(pos-1, "(") && p.isKeyword(pos-2, "ANY") && p.isComparison(pos-3):
+		if typeName := p.columnTypeBefore(pos - 4); typeName != UnknownParamType {
+			return normalizeTypeName(typeName, true)
+		}
+		return UnknownParamType
+	}
+
+	// col IN ($1, $2, ...)
+	start := pos - 1
+	for p.is(start, ",") && p.isParam(start-1) {
+		start -= 2
+	}
+	if p.is(start, "(") && p.isKeyword(start-1, "IN") {
+		return p.columnTypeBefore(start - 2)
+	}
+	return UnknownParamType
+}
+
+// is check text of not string token
+func (p paramInference) is(pos int, text string) bool {
+	return pos >= 0 && pos < len(p.tokens) && p.tokens[pos].Kind != TokenString && p.tokens[pos].Text == text
+}
+
+func (p paramInference) isParam(pos int) bool {
+	return pos >= 0 && pos < len(p.tokens) && p.tokens[pos].Kind == TokenParam
+}
+
+func (p paramInference) isKeyword(pos int, keyword string) bool {
+	return pos >= 0 && pos < len(p.tokens) && p.tokens[pos].IsKeyword(keyword)
+}
+
+func (p paramInference) isComparison(pos int) bool {
+	if pos < 0 || pos >= len(p.tokens) || p.tokens[pos].Kind != TokenOperator {
+		return false
+	}
+	for _, op := range comparisonOperators {
+		if p.tokens[pos].Text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// columnTypeBefore return type of column, which name ends at tokens[end], like col or alias.col
+func (p paramInference) columnTypeBefore(end int) string {
+	if end < 0 || end >= len(p.tokens) || !isIdentifierToken(p.tokens[end]) {
+		return UnknownParamType
+	}
+	qualifier := ""
+	if p.is(end-1, ".") && end >= 2 && isIdentifierToken(p.tokens[end-2]) {
+		qualifier = IdentifierValue(p.tokens[end-2])
+	}
+	return p.columnType(qualifier, IdentifierValue(p.tokens[end]))
+}
+
+// columnTypeAfter return type of column, which name starts at tokens[start]
+func (p paramInference) columnTypeAfter(start int) string {
+	end := start
+	for p.is(end+1, ".") && end+2 < len(p.tokens) && isIdentifierToken(p.tokens[end+2]) {
+		end += 2
+	}
+	return p.columnTypeBefore(end)
+}
+
+// columnType find the column in referenced tables. Column of table with qualifier name is preferred,
+// otherwise type is inferred only if the columns with the name have same type in all referenced tables.
+func (p paramInference) columnType(qualifier, column string) string {
+	if typeName, ok := p.columns[qualifier][column]; ok {
+		return typeName
+	}
+
+	res := UnknownParamType
+	for _, table := range p.tables {
+		typeName, ok := p.columns[table][column]
+		switch {
+		case !ok:
+			continue
+		case res == UnknownParamType:
+			res = typeName
+		case res != typeName:
+			return UnknownParamType
+		}
+	}
+	return res
+}
+
+// insertTypes map placeholders from VALUES of INSERT INTO table (columns) to types of the columns
+func (p paramInference) insertTypes() map[int]string {
+	res := map[int]string{}
+	for i := range p.tokens {
+		if !p.isKeyword(i, "INSERT") || !p.isKeyword(i+1, "INTO") {
+			continue
+		}
+
+		pos := i + 2
+		table := ""
+		for pos < len(p.tokens) && isIdentifierToken(p.tokens[pos]) {
+			table = IdentifierValue(p.tokens[pos])
+			pos++
+			if !p.is(pos, ".") {
+				break
+			}
+			pos++
+		}
+		if p.isKeyword(pos, "AS") {
+			pos += 2
+		}
+		if !p.is(pos, "(") {
+			continue
+		}
+
+		var columns []string
+		for pos++; pos < len(p.tokens) && !p.is(pos, ")"); pos++ {
+			if isIdentifierToken(p.tokens[pos]) {
+				columns = append(columns, IdentifierValue(p.tokens[pos]))
+			}
+		}
+		pos++
+		if !p.isKeyword(pos, "VALUES") {
+			continue
+		}
+
+		pos++
+		for p.is(pos, "(") {
+			end := skipParentheses(p.tokens, pos)
+			p.insertRowTypes(res, table, columns, pos+1, end-1)
+			pos = end
+			if !p.is(pos, ",") {
+				break
+			}
+			pos++
+		}
+	}
+	return res
+}
+
+// insertRowTypes set types of placeholders, which are whole items of values row tokens[start:end]
+func (p paramInference) insertRowTypes(res map[int]string, table string, columns []string, start, end int) {
+	item := 0
+	itemStart := start
+	depth := 0
+	for pos := start; pos <= end && pos < len(p.tokens); pos++ {
+		switch {
+		case p.is(pos, "("):
+			depth++
+		case p.is(pos, ")") && pos < end:
+			depth--
+		case depth == 0 && (pos == end || p.is(pos, ",")):
+			if n := paramNumber(p.tokens[itemStart]); n > 0 && pos == itemStart+1 && item < len(columns) {
+				if typeName, ok := p.columns[table][columns[item]]; ok {
+					if _, exists := res[n]; !exists {
+						res[n] = typeName
+					}
+				}
+			}
+			item++
+			itemStart = pos + 1
+		}
+	}
+}
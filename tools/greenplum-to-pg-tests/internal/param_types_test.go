@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testParamsSchemaDump = `CREATE TABLE public.hosts (
+    hostname character varying(255) NOT NULL,
+    updated timestamp without time zone,
+    max_connections integer DEFAULT 100,
+    tags text[],
+    CONSTRAINT hosts_pk PRIMARY KEY (hostname)
+)
+DISTRIBUTED BY (hostname)
+;
+CREATE TABLE stat.events (
+    id bigint,
+    hostname character varying(255),
+    value double precision,
+    created timestamp(3) with time zone ENCODING (compresstype=zlib),
+    kind my_enum
+)
+DISTRIBUTED RANDOMLY
+;
+`
+
+func TestSchemaColumnTypes(t *testing.T) {
+	schema := NewPgSchema()
+	schema.Read(strings.NewReader(testParamsSchemaDump))
+
+	hosts := map[string]string{
+		"__stub_primary_key": "int4",
+		"hostname":           "varchar",
+		"updated":            "timestamp",
+		"max_connections":    "int4",
+		"tags":               "_text",
+	}
+	events := map[string]string{
+		"__stub_primary_key": "int4",
+		"id":                 "int8",
+		"hostname":           "varchar",
+		"value":              "float8",
+		"created":            "timestamptz",
+	}
+	require.Equal(t, ColumnTypes{
+		"public___hosts": hosts,
+		"hosts":          hosts,
+		"stat___events":  events,
+		"events":         events,
+	}, schema.ColumnTypes())
+}
+
+func TestInferParamTypes(t *testing.T) {
+	schema := NewPgSchema()
+	schema.Read(strings.NewReader(testParamsSchemaDump))
+	columns := schema.ColumnTypes()
+
+	table := []struct {
+		name  string
+		query string
+		types []string
+	}{
+		{
+			name:  "NoParams",
+			query: "SELECT '$1', $$ $2 $$ FROM public___hosts -- $3",
+		},
+		{
+			name:  "Casts",
+			query: "SELECT $1::int, CAST($2 AS character varying(10)), $3::pg_catalog.timestamp(3) with time zone, $4::numeric(10, 2)[], $5::my_type",
+			types: []string{"int4", "varchar", "timestamptz", "_numeric", UnknownParamType},
+		},
+		{
+			name: "InsertOnConflict",
+			query: `INSERT INTO public___hosts (hostname, updated, max_connections) VALUES ($1, NOW(), $2)
+				ON CONFLICT (hostname) DO UPDATE SET updated = NOW(), max_connections = $2`,
+			types: []string{"varchar", "int4"},
+		},
+		{
+			name:  "InsertManyRows",
+			query: "INSERT INTO events AS e (id, value, created) VALUES ($1, $2, $3), ($4, coalesce($5, 0), $6::text)",
+			types: []string{"int8", "float8", "timestamptz", "int8", UnknownParamType, "text"},
+		},
+		{
+			name:  "Comparisons",
+			query: "SELECT * FROM stat___events e WHERE e.id = $1 AND $2 < value AND created BETWEEN $3 AND $4 AND kind = $5",
+			types: []string{"int8", "float8", "timestamptz", "timestamptz", UnknownParamType},
+		},
+		{
+			name:  "InAnyLike",
+			query: "SELECT * FROM hosts WHERE hostname IN ($1, $2) AND max_connections = ANY($3) AND hostname LIKE $4",
+			types: []string{"varchar", "varchar", "_int4", "text"},
+		},
+		{
+			name:  "SameColumnInJoinedTables",
+			query: "SELECT * FROM hosts h JOIN events e ON e.hostname = h.hostname WHERE hostname = $1 AND events.id = $2",
+			types: []string{"varchar", "int8"},
+		},
+		{
+			name:  "LimitOffsetAndGaps",
+			query: "SELECT * FROM unknown_table WHERE a = $1 LIMIT $3 OFFSET $4",
+			types: []string{UnknownParamType, UnknownParamType, "int8", "int8"},
+		},
+		{
+			name:  "TooBigParamNumber",
+			query: "SELECT * FROM hosts WHERE hostname = $1 AND max_connections = $65536 OR max_connections = $99999999999999999999",
+			types: []string{"varchar"},
+		},
+		{
+			name:  "UnknownFunctionArgs",
+			query: "SELECT set_config($1, $2, $3) as myvar",
+			types: []string{UnknownParamType, UnknownParamType, UnknownParamType},
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.types, InferParamTypes(test.query, columns))
+		})
+	}
+}
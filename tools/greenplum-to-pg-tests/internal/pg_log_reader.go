@@ -0,0 +1,477 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SessionLogReader read query log records one by one, return io.EOF after last record
+type SessionLogReader interface {
+	Next() (SessionLogRecord, error)
+	Close() error
+}
+
+// PositionedLogReader is SessionLogReader, which know offset in the input after last read record.
+// The offset saved in checkpoint for seek the input on resume.
+type PositionedLogReader interface {
+	SessionLogReader
+	Position() int64
+}
+
+type jsonSessionLogReader struct {
+	io.Closer
+	decoder *json.Decoder
+	start   int64
+}
+
+// NewJsonSessionLogReader read own json-lines log format
+func NewJsonSessionLogReader(reader io.ReadCloser) PositionedLogReader {
+	return NewJsonSessionLogReaderAt(reader, 0)
+}
+
+// NewJsonSessionLogReaderAt read own json-lines log format from the reader, seeked to start offset of the log
+func NewJsonSessionLogReaderAt(reader io.ReadCloser, start int64) PositionedLogReader {
+	return &jsonSessionLogReader{Closer: reader, decoder: json.NewDecoder(reader), start: start}
+}
+
+func (r *jsonSessionLogReader) Next() (SessionLogRecord, error) {
+	var record SessionLogRecord
+	err := r.decoder.Decode(&record)
+	return record, err
+}
+
+func (r *jsonSessionLogReader) Position() int64 {
+	return r.start + r.decoder.InputOffset()
+}
+
+// pgLogEntry is one message of postgres/greenplum server log
+type pgLogEntry struct {
+	pid        int
+	sessionKey string
+	txKey      string // transaction id from csvlog, empty if unknown
+	severity   string
+	message    string
+}
+
+var (
+	statementMessageRegexp  = regexp.MustCompile(`(?s)^(?:duration: [\d.]+ ms\s+)?(?:statement|execute [^:]*): (.*)$`)
+	disconnectMessagePrefix = "disconnection: "
+	beginRegexp             = regexp.MustCompile(`(?is)^\s*(BEGIN|START\s+TRANSACTION)\b`)
+	rollbackRegexp          = regexp.MustCompile(`(?is)^\s*(ROLLBACK|ABORT)\s*(WORK|TRANSACTION)?\s*;?\s*$`)
+)
+
+// pgLogSessionizer convert server log messages to session log records.
+// Transactions detected by transaction id of entries if it is known (csvlog), else by BEGIN/COMMIT statements
+// and statement outside of explicit transaction is own transaction.
+// Transaction fail if server log error while the transaction.
+type pgLogSessionizer struct {
+	sessions      map[string]*pgLogSessionState
+	nextSessionID int
+	ready         []SessionLogRecord
+}
+
+type pgLogSessionState struct {
+	pid              int
+	id               int
+	transactionCount int
+	inExplicitTx     bool
+	failed           bool
+	txKey            string // transaction id of pending statements, empty if unknown
+	pending          []string
+}
+
+func newPgLogSessionizer() *pgLogSessionizer {
+	return &pgLogSessionizer{
+		sessions: make(map[string]*pgLogSessionState),
+	}
+}
+
+func (s *pgLogSessionizer) process(entry pgLogEntry) {
+	session := s.sessions[entry.sessionKey]
+	if session == nil {
+		s.nextSessionID++
+		session = &pgLogSessionState{pid: entry.pid, id: s.nextSessionID}
+		s.sessions[entry.sessionKey] = session
+	}
+
+	if entry.txKey != "" && session.txKey != "" && entry.txKey != session.txKey {
+		// next transaction of the session started, even without BEGIN/COMMIT in the log
+		s.flush(session)
+	}
+
+	switch entry.severity {
+	case "ERROR", "FATAL", "PANIC":
+		session.failed = true
+		return
+	case "LOG":
+		// pass
+	default:
+		return
+	}
+
+	if strings.HasPrefix(entry.message, disconnectMessagePrefix) {
+		s.flush(session)
+		delete(s.sessions, entry.sessionKey)
+		return
+	}
+
+	match := statementMessageRegexp.FindStringSubmatch(entry.message)
+	if match == nil {
+		return
+	}
+	queryText := match[1]
+	if entry.txKey != "" {
+		session.txKey = entry.txKey
+	}
+
+	switch {
+	case beginRegexp.MatchString(queryText):
+		s.flush(session)
+		session.inExplicitTx = true
+		session.pending = append(session.pending, queryText)
+	case IsTransactionControl(queryText):
+		session.pending = append(session.pending, queryText)
+		if rollbackRegexp.MatchString(queryText) {
+			session.failed = true
+		}
+		s.flush(session)
+	case session.inExplicitTx || session.txKey != "":
+		// transaction with known id completed when next transaction id appear
+		session.pending = append(session.pending, queryText)
+	default:
+		// keep the statement pending until next statement, for catch error message after it
+		s.flush(session)
+		session.pending = append(session.pending, queryText)
+	}
+}
+
+func (s *pgLogSessionizer) flush(session *pgLogSessionState) {
+	if len(session.pending) > 0 {
+		for i, queryText := range session.pending {
+			s.ready = append(s.ready, SessionLogRecord{
+				ProcessID:          session.pid,
+				SessionID:          session.id,
+				QueryCount:         i,
+				Query:              queryText,
+				TransactionCount:   session.transactionCount,
+				TransactionSuccess: !session.failed,
+			})
+		}
+		session.transactionCount++
+	}
+	session.pending = nil
+	session.failed = false
+	session.inExplicitTx = false
+	session.txKey = ""
+}
+
+func (s *pgLogSessionizer) flushAll() {
+	sessions := make([]*pgLogSessionState, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].id < sessions[j].id
+	})
+	for _, session := range sessions {
+		s.flush(session)
+	}
+	s.sessions = make(map[string]*pgLogSessionState)
+}
+
+func (s *pgLogSessionizer) pop() (SessionLogRecord, bool) {
+	if len(s.ready) == 0 {
+		return SessionLogRecord{}, false
+	}
+	res := s.ready[0]
+	s.ready = s.ready[1:]
+	return res, true
+}
+
+type csvLogReader struct {
+	io.Closer
+	csv        *csv.Reader
+	sessionize *pgLogSessionizer
+	eof        bool
+}
+
+// NewCsvLogReader read postgres or greenplum server log in csvlog format,
+// format detected by columns count for every line
+func NewCsvLogReader(reader io.ReadCloser) SessionLogReader {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+	return &csvLogReader{
+		Closer:     reader,
+		csv:        csvReader,
+		sessionize: newPgLogSessionizer(),
+	}
+}
+
+func (r *csvLogReader) Next() (SessionLogRecord, error) {
+	for {
+		if record, ok := r.sessionize.pop(); ok {
+			return record, nil
+		}
+		if r.eof {
+			return SessionLogRecord{}, io.EOF
+		}
+
+		line, err := r.csv.Read()
+		if errors.Is(err, io.EOF) {
+			r.eof = true
+			r.sessionize.flushAll()
+			continue
+		}
+		if err != nil {
+			return SessionLogRecord{}, err
+		}
+
+		entry, err := parseCsvLogLine(line)
+		if err != nil {
+			return SessionLogRecord{}, err
+		}
+		r.sessionize.process(entry)
+	}
+}
+
+const (
+	pgCsvLogMinColumns = 22
+	gpCsvLogMinColumns = 29
+)
+
+func parseCsvLogLine(line []string) (pgLogEntry, error) {
+	if len(line) >= gpCsvLogMinColumns && strings.HasPrefix(line[9], "con") {
+		// greenplum: event_time, user_name, database_name, process_id, thread_id, remote_host, remote_port,
+		// session_start_time, transaction_id, gp_session_id, gp_command_count, gp_segment, slice_id,
+		// distr_tranx_id, local_tranx_id, sub_tranx_id, event_severity, sql_state_code, event_message, ...
+		pid, err := strconv.Atoi(strings.TrimPrefix(line[3], "p"))
+		if err != nil {
+			return pgLogEntry{}, fmt.Errorf("failed to parse greenplum process id %q: %w", line[3], err)
+		}
+		// distributed transaction id is same on coordinator and segments, local transaction id used without it
+		txKey := csvLogTransactionID(line[13])
+		if txKey == "" {
+			txKey = csvLogTransactionID(line[8])
+		}
+		return pgLogEntry{
+			pid:        pid,
+			sessionKey: line[9],
+			txKey:      txKey,
+			severity:   line[16],
+			message:    line[18],
+		}, nil
+	}
+
+	if len(line) >= pgCsvLogMinColumns {
+		// postgres: log_time, user_name, database_name, process_id, connection_from, session_id,
+		// session_line_num, command_tag, session_start_time, virtual_transaction_id, transaction_id,
+		// error_severity, sql_state_code, message, ...
+		pid, err := strconv.Atoi(line[3])
+		if err != nil {
+			return pgLogEntry{}, fmt.Errorf("failed to parse process id %q: %w", line[3], err)
+		}
+		// transaction_id is assigned on first write only, so it is 0 for statements logged before the write
+		// and differ inside one transaction. Virtual transaction id is assigned for every transaction at start.
+		txKey := csvLogTransactionID(line[9])
+		if txKey == "" {
+			txKey = csvLogTransactionID(line[10])
+		}
+		return pgLogEntry{
+			pid:        pid,
+			sessionKey: line[5],
+			txKey:      txKey,
+			severity:   line[11],
+			message:    line[13],
+		}, nil
+	}
+
+	return pgLogEntry{}, fmt.Errorf("unexpected csvlog columns count: %v", len(line))
+}
+
+// csvLogTransactionID return transaction id from csvlog column or empty string for not assigned ids:
+// empty, 0 or virtual id with 0 local part
+func csvLogTransactionID(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" || strings.HasSuffix(value, "/0") {
+		return ""
+	}
+	return value
+}
+
+type stderrLogReader struct {
+	io.Closer
+	scanner      *bufio.Scanner
+	prefix       *regexp.Regexp
+	sessionize   *pgLogSessionizer
+	current      *pgLogEntry
+	eof          bool
+	pidIndex     int
+	sessionIndex int
+}
+
+// NewStderrLogReader read postgres or greenplum server log in stderr format,
+// linePrefix is log_line_prefix setting of the server
+func NewStderrLogReader(reader io.ReadCloser, linePrefix string) (SessionLogReader, error) {
+	prefixRegexp, err := compileLogLinePrefix(linePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	pidIndex := prefixRegexp.SubexpIndex("pid")
+	sessionIndex := prefixRegexp.SubexpIndex("session")
+	if pidIndex < 0 && sessionIndex < 0 {
+		return nil, fmt.Errorf("log_line_prefix must contains %%p or %%c for detect sessions: %q", linePrefix)
+	}
+
+	const maxLineSize = 64 * 1024 * 1024
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxLineSize)
+
+	return &stderrLogReader{
+		Closer:       reader,
+		scanner:      scanner,
+		prefix:       prefixRegexp,
+		sessionize:   newPgLogSessionizer(),
+		pidIndex:     pidIndex,
+		sessionIndex: sessionIndex,
+	}, nil
+}
+
+func (r *stderrLogReader) Next() (SessionLogRecord, error) {
+	for {
+		if record, ok := r.sessionize.pop(); ok {
+			return record, nil
+		}
+		if r.eof {
+			return SessionLogRecord{}, io.EOF
+		}
+
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return SessionLogRecord{}, err
+			}
+			r.eof = true
+			r.completeEntry()
+			r.sessionize.flushAll()
+			continue
+		}
+
+		if err := r.processLine(r.scanner.Text()); err != nil {
+			return SessionLogRecord{}, err
+		}
+	}
+}
+
+func (r *stderrLogReader) processLine(line string) error {
+	match := r.prefix.FindStringSubmatch(line)
+	if match == nil {
+		// multiline messages continue with tab on next lines
+		if r.current != nil {
+			r.current.message += "\n" + strings.TrimPrefix(line, "\t")
+		}
+		return nil
+	}
+
+	r.completeEntry()
+
+	entry := &pgLogEntry{
+		severity: match[r.prefix.SubexpIndex("severity")],
+		message:  match[r.prefix.SubexpIndex("message")],
+	}
+	if r.pidIndex >= 0 {
+		pid, err := strconv.Atoi(match[r.pidIndex])
+		if err != nil {
+			return fmt.Errorf("failed to parse pid %q: %w", match[r.pidIndex], err)
+		}
+		entry.pid = pid
+		entry.sessionKey = match[r.pidIndex]
+	}
+	if r.sessionIndex >= 0 {
+		entry.sessionKey = match[r.sessionIndex]
+	}
+	r.current = entry
+	return nil
+}
+
+func (r *stderrLogReader) completeEntry() {
+	if r.current != nil {
+		r.sessionize.process(*r.current)
+		r.current = nil
+	}
+}
+
+const (
+	logTimePattern = `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?(?: \S+)?`
+	anyPattern     = `.*?`
+)
+
+var logLinePrefixEscapes = map[byte]string{
+	'a': anyPattern,
+	'u': anyPattern,
+	'd': anyPattern,
+	'r': anyPattern,
+	'h': anyPattern,
+	'b': anyPattern,
+	'i': anyPattern,
+	'p': `(?P<pid>\d+)`,
+	'P': `\d*`,
+	't': logTimePattern,
+	'm': logTimePattern,
+	's': logTimePattern,
+	'n': `\d+(?:\.\d+)?`,
+	'e': `[0-9A-Z]{5}`,
+	'c': `(?P<session>[0-9a-f]+\.[0-9a-f]+)`,
+	'l': `\d+`,
+	'v': `\S*`,
+	'x': `\d+`,
+	'Q': `-?\d+`,
+	'q': ``,
+}
+
+// compileLogLinePrefix convert log_line_prefix setting to regexp for parse log lines
+func compileLogLinePrefix(linePrefix string) (*regexp.Regexp, error) {
+	buf := &strings.Builder{}
+	buf.WriteString("^")
+	for i := 0; i < len(linePrefix); i++ {
+		if linePrefix[i] != '%' {
+			buf.WriteString(regexp.QuoteMeta(linePrefix[i : i+1]))
+			continue
+		}
+
+		i++
+		padding := false
+		for i < len(linePrefix) && (linePrefix[i] == '-' || isDigit(rune(linePrefix[i]))) {
+			padding = true
+			i++
+		}
+		if i >= len(linePrefix) {
+			return nil, fmt.Errorf("unexpected end of log_line_prefix: %q", linePrefix)
+		}
+
+		if linePrefix[i] == '%' {
+			buf.WriteString("%")
+			continue
+		}
+
+		pattern, ok := logLinePrefixEscapes[linePrefix[i]]
+		if !ok {
+			return nil, fmt.Errorf("unsupported escape %%%c in log_line_prefix: %q", linePrefix[i], linePrefix)
+		}
+		if padding {
+			pattern = ` *` + pattern + ` *`
+		}
+		buf.WriteString(pattern)
+	}
+	buf.WriteString(`(?P<severity>[A-Z]+):  (?P<message>.*)$`)
+
+	return regexp.Compile(buf.String())
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAllRecords(t *testing.T, reader SessionLogReader) []SessionLogRecord {
+	var res []SessionLogRecord
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return res
+		}
+		require.NoError(t, err)
+		res = append(res, record)
+	}
+}
+
+func TestJsonSessionLogReaderPosition(t *testing.T) {
+	log := `{"query":"SELECT 1","transaction_success":true}
+{"query":"SELECT 2","transaction_success":true}
+{"query":"SELECT 3","transaction_success":true}
+`
+	reader := NewJsonSessionLogReader(io.NopCloser(strings.NewReader(log)))
+	_, err := reader.Next()
+	require.NoError(t, err)
+	position := reader.Position()
+	require.Equal(t, int64(strings.Index(log, "\n")), position)
+
+	// continue from the position as after seek on resume
+	reader = NewJsonSessionLogReaderAt(io.NopCloser(strings.NewReader(log[position:])), position)
+	records := readAllRecords(t, reader)
+	require.Equal(t, []string{"SELECT 2", "SELECT 3"}, []string{records[0].Query, records[1].Query})
+	require.Equal(t, int64(len(log)-1), reader.Position())
+}
+
+func TestCsvLogReader(t *testing.T) {
+	log := `2024-05-01 10:00:00.000 UTC,"u","db",100,"[local]",6632.64,1,"idle",2024-05-01 10:00:00 UTC,3/1,0,LOG,00000,"statement: BEGIN",,,,,,,,,"psql"
+2024-05-01 10:00:00.001 UTC,"u","db",100,"[local]",6632.64,2,"idle",2024-05-01 10:00:00 UTC,3/1,0,LOG,00000,"statement: SELECT 1,
+  2",,,,,,,,,"psql"
+2024-05-01 10:00:00.002 UTC,"u","db",101,"[local]",6632.65,1,"idle",2024-05-01 10:00:00 UTC,4/1,0,LOG,00000,"statement: SELECT bad",,,,,,,,,"psql"
+2024-05-01 10:00:00.003 UTC,"u","db",101,"[local]",6632.65,2,"SELECT",2024-05-01 10:00:00 UTC,4/1,0,ERROR,42703,"column ""bad"" does not exist",,,,,,"SELECT bad",8,,"psql"
+2024-05-01 10:00:00.004 UTC,"u","db",100,"[local]",6632.64,3,"idle",2024-05-01 10:00:00 UTC,3/1,0,LOG,00000,"statement: COMMIT",,,,,,,,,"psql"
+2024-05-01 10:00:00.005 UTC,"u","db",101,"[local]",6632.65,3,"idle",2024-05-01 10:00:00 UTC,4/2,0,LOG,00000,"statement: SELECT 2",,,,,,,,,"psql"
+`
+	records := readAllRecords(t, NewCsvLogReader(io.NopCloser(strings.NewReader(log))))
+	require.Equal(t, []SessionLogRecord{
+		{ProcessID: 100, SessionID: 1, QueryCount: 0, Query: "BEGIN", TransactionCount: 0, TransactionSuccess: true},
+		{ProcessID: 100, SessionID: 1, QueryCount: 1, Query: "SELECT 1,\n  2", TransactionCount: 0, TransactionSuccess: true},
+		{ProcessID: 100, SessionID: 1, QueryCount: 2, Query: "COMMIT", TransactionCount: 0, TransactionSuccess: true},
+		{ProcessID: 101, SessionID: 2, QueryCount: 0, Query: "SELECT bad", TransactionCount: 0, TransactionSuccess: false},
+		{ProcessID: 101, SessionID: 2, QueryCount: 0, Query: "SELECT 2", TransactionCount: 1, TransactionSuccess: true},
+	}, records)
+}
+
+func TestCsvLogReaderTransactionIDs(t *testing.T) {
+	// transactions without BEGIN/COMMIT in the log, sessions are interleaved
+	log := `2024-05-01 10:00:00.000 UTC,"u","db",100,"[local]",6632.64,1,"idle",2024-05-01 10:00:00 UTC,3/5,0,LOG,00000,"statement: SELECT 1",,,,,,,,,"app"
+2024-05-01 10:00:00.001 UTC,"u","db",101,"[local]",6632.65,1,"idle",2024-05-01 10:00:00 UTC,4/7,0,LOG,00000,"statement: SELECT 10",,,,,,,,,"app"
+2024-05-01 10:00:00.002 UTC,"u","db",100,"[local]",6632.64,2,"idle",2024-05-01 10:00:00 UTC,3/5,0,LOG,00000,"statement: INSERT INTO t VALUES (1)",,,,,,,,,"app"
+2024-05-01 10:00:00.003 UTC,"u","db",100,"[local]",6632.64,3,"idle",2024-05-01 10:00:00 UTC,3/6,0,LOG,00000,"statement: SELECT bad",,,,,,,,,"app"
+2024-05-01 10:00:00.004 UTC,"u","db",101,"[local]",6632.65,2,"idle",2024-05-01 10:00:00 UTC,4/7,750,LOG,00000,"statement: UPDATE t SET a = 2",,,,,,,,,"app"
+2024-05-01 10:00:00.005 UTC,"u","db",100,"[local]",6632.64,4,"SELECT",2024-05-01 10:00:00 UTC,3/6,0,ERROR,42703,"column ""bad"" does not exist",,,,,,"SELECT bad",8,,"app"
+2024-05-01 10:00:00.006 UTC,"u","db",100,"[local]",6632.64,5,"idle",2024-05-01 10:00:00 UTC,3/7,0,LOG,00000,"statement: SELECT 2",,,,,,,,,"app"
+2024-05-01 10:00:00.007 UTC,"u","db",101,"[local]",6632.65,3,"idle",2024-05-01 10:00:00 UTC,4/8,0,LOG,00000,"statement: SELECT 11",,,,,,,,,"app"
+`
+	records := readAllRecords(t, NewCsvLogReader(io.NopCloser(strings.NewReader(log))))
+	require.Equal(t, []SessionLogRecord{
+		{ProcessID: 100, SessionID: 1, QueryCount: 0, Query: "SELECT 1", TransactionCount: 0, TransactionSuccess: true},
+		{ProcessID: 100, SessionID: 1, QueryCount: 1, Query: "INSERT INTO t VALUES (1)", TransactionCount: 0, TransactionSuccess: true},
+		{ProcessID: 100, SessionID: 1, QueryCount: 0, Query: "SELECT bad", TransactionCount: 1, TransactionSuccess: false},
+		{ProcessID: 101, SessionID: 2, QueryCount: 0, Query: "SELECT 10", TransactionCount: 0, TransactionSuccess: true},
+		{ProcessID: 101, SessionID: 2, QueryCount: 1, Query: "UPDATE t SET a = 2", TransactionCount: 0, TransactionSuccess: true},
+		{ProcessID: 100, SessionID: 1, QueryCount: 0, Query: "SELECT 2", TransactionCount: 2, TransactionSuccess: true},
+		{ProcessID: 101, SessionID: 2, QueryCount: 0, Query: "SELECT 11", TransactionCount: 1, TransactionSuccess: true},
+	}, records)
+}
+
+func TestCsvLogReaderGreenplum(t *testing.T) {
+	log := `2024-05-01 10:00:00.000000 UTC,"u","db",p1234,th1,"10.0.0.1","5000",2024-05-01 10:00:00 UTC,0,con42,cmd1,seg-1,,,,sx1,"LOG","00000","statement: SELECT 1",,,,,,"SELECT 1",0,,"postgres.c",1000,
+`
+	records := readAllRecords(t, NewCsvLogReader(io.NopCloser(strings.NewReader(log))))
+	require.Equal(t, []SessionLogRecord{
+		{ProcessID: 1234, SessionID: 1, Query: "SELECT 1", TransactionSuccess: true},
+	}, records)
+}
+
+func TestStderrLogReader(t *testing.T) {
+	log := `2024-05-01 10:00:00.000 UTC [100] LOG:  statement: SELECT a
+	FROM t
+2024-05-01 10:00:00.001 UTC [100] ERROR:  relation "t" does not exist at character 15
+2024-05-01 10:00:00.001 UTC [100] STATEMENT:  SELECT a
+	FROM t
+2024-05-01 10:00:00.002 UTC [100] LOG:  execute <unnamed>: SELECT $1
+2024-05-01 10:00:00.002 UTC [100] DETAIL:  parameters: $1 = '1'
+2024-05-01 10:00:00.003 UTC [100] LOG:  disconnection: session time: 0:00:00.003 user=u database=db host=[local]
+`
+	reader, err := NewStderrLogReader(io.NopCloser(strings.NewReader(log)), "%m [%p] ")
+	require.NoError(t, err)
+
+	records := readAllRecords(t, reader)
+	require.Equal(t, []SessionLogRecord{
+		{ProcessID: 100, SessionID: 1, Query: "SELECT a\nFROM t", TransactionCount: 0, TransactionSuccess: false},
+		{ProcessID: 100, SessionID: 1, Query: "SELECT $1", TransactionCount: 1, TransactionSuccess: true},
+	}, records)
+}
+
+func TestCompileLogLinePrefix(t *testing.T) {
+	re, err := compileLogLinePrefix("%t [%p-%l] %q%u@%d %% %c ")
+	require.NoError(t, err)
+
+	match := re.FindStringSubmatch("2024-05-01 10:00:00 UTC [77-3] user@db % 6632.4d LOG:  statement: SELECT 1")
+	require.NotNil(t, match)
+	require.Equal(t, "77", match[re.SubexpIndex("pid")])
+	require.Equal(t, "6632.4d", match[re.SubexpIndex("session")])
+	require.Equal(t, "statement: SELECT 1", match[re.SubexpIndex("message")])
+
+	_, err = compileLogLinePrefix("%z")
+	require.Error(t, err)
+}
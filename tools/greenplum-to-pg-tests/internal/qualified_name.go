@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// QualifiedName is dotted name in query tokens, like schema.table or "schema".table.column
+type QualifiedName struct {
+	Start int   // index of first token of the name
+	End   int   // index of token after the name
+	Parts []int // indexes of identifier tokens, last part may be * operator
+}
+
+// FindQualifiedNames return names with two or more parts. Whitespaces and comments around dots are allowed.
+func FindQualifiedNames(tokens []Token) []QualifiedName {
+	var res []QualifiedName
+	for i := 0; i < len(tokens); {
+		if prev := PrevMeaningful(tokens, i); !isIdentifierToken(tokens[i]) || prev >= 0 && tokens[prev].Text == "." {
+			i++
+			continue
+		}
+
+		name := QualifiedName{Start: i, End: i + 1, Parts: []int{i}}
+		for {
+			dot := nextMeaningful(tokens, name.End)
+			if dot < 0 || tokens[dot].Text != "." {
+				break
+			}
+			part := nextMeaningful(tokens, dot+1)
+			if part < 0 {
+				break
+			}
+			if tokens[part].Kind == TokenOperator && tokens[part].Text == "*" {
+				name.Parts = append(name.Parts, part)
+				name.End = part + 1
+				break
+			}
+			if !isIdentifierToken(tokens[part]) {
+				break
+			}
+			name.Parts = append(name.Parts, part)
+			name.End = part + 1
+		}
+
+		if len(name.Parts) > 1 {
+			res = append(res, name)
+		}
+		i = name.End
+	}
+	return res
+}
+
+// ReplaceQualifiedNames lex the text and call replace for every qualified name.
+// Text of the name tokens replaced by result of the function if it returns true.
+func ReplaceQualifiedNames(text string, replace func(tokens []Token, name QualifiedName) (string, bool)) string {
+	tokens := LexSQL(text)
+	names := FindQualifiedNames(tokens)
+	if len(names) == 0 {
+		return text
+	}
+
+	buf := &strings.Builder{}
+	pos := 0
+	for _, name := range names {
+		newText, ok := replace(tokens, name)
+		if !ok {
+			continue
+		}
+		for _, token := range tokens[pos:name.Start] {
+			buf.WriteString(token.Text)
+		}
+		buf.WriteString(newText)
+		pos = name.End
+	}
+	for _, token := range tokens[pos:] {
+		buf.WriteString(token.Text)
+	}
+	return buf.String()
+}
+
+// IdentifierValue return name of identifier as postgres see it: unquoted names folded to lower case
+func IdentifierValue(token Token) string {
+	switch {
+	case token.Kind == TokenIdentifier:
+		return strings.ToLower(token.Text)
+	case token.Kind == TokenQuotedIdentifier && strings.HasPrefix(token.Text, `"`):
+		value := strings.TrimSuffix(strings.TrimPrefix(token.Text, `"`), `"`)
+		return strings.ReplaceAll(value, `""`, `"`)
+	default:
+		return token.Text
+	}
+}
+
+var plainIdentifierRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// QuoteIdentifier return identifier text for the value: value as is if postgres read it without changes,
+// quoted identifier otherwise, for example for names with upper case letters
+func QuoteIdentifier(value string) string {
+	if plainIdentifierRegexp.MatchString(value) {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
+
+func isIdentifierToken(token Token) bool {
+	return token.Kind == TokenIdentifier || token.Kind == TokenQuotedIdentifier
+}
+
+// PrevMeaningful return index of last meaningful token before end or -1
+func PrevMeaningful(tokens []Token, end int) int {
+	end--
+	for end >= 0 && !tokens[end].IsMeaningful() {
+		end--
+	}
+	return end
+}
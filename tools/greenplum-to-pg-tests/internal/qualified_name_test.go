@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindQualifiedNames(t *testing.T) {
+	table := []struct {
+		name  string
+		query string
+		names [][]string
+	}{
+		{
+			name:  "Simple",
+			query: "SELECT a.b, c FROM s.t",
+			names: [][]string{{"a", "b"}, {"s", "t"}},
+		},
+		{
+			name:  "QuotedAndSpaces",
+			query: `SELECT "S" . t /* c */ .col, s.t.*`,
+			names: [][]string{{`"S"`, "t", "col"}, {"s", "t", "*"}},
+		},
+		{
+			name:  "SkipLiteralsAndComments",
+			query: "SELECT 'a.b', E'c.d', $$e.f$$, 1.5 -- g.h\n/* i.j */",
+		},
+		{
+			name:  "FieldOfExpression",
+			query: "SELECT (r).a.b",
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			tokens := LexSQL(test.query)
+			var names [][]string
+			for _, name := range FindQualifiedNames(tokens) {
+				var parts []string
+				for _, part := range name.Parts {
+					parts = append(parts, tokens[part].Text)
+				}
+				names = append(names, parts)
+			}
+			require.Equal(t, test.names, names)
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	require.Equal(t, "s___t", QuoteIdentifier("s___t"))
+	require.Equal(t, `"Sch___tbl"`, QuoteIdentifier("Sch___tbl"))
+	require.Equal(t, `"s___a b""c"`, QuoteIdentifier(`s___a b"c`))
+	require.Equal(t, `"1s___t"`, QuoteIdentifier("1s___t"))
+}
+
+func TestReplaceSchemaAndName(t *testing.T) {
+	table := []struct {
+		text       string
+		schemaName string
+		name       string
+		result     string
+	}{
+		{
+			text:       "CREATE TABLE s.t (",
+			schemaName: "s",
+			name:       "t",
+			result:     "CREATE TABLE s___t (",
+		},
+		{
+			text:       `CREATE VIEW s."T" AS SELECT 's."T"' FROM "s"."T", s.t`,
+			schemaName: "s",
+			name:       `"T"`,
+			result:     `CREATE VIEW "s___T" AS SELECT 's."T"' FROM "s___T", s.t`,
+		},
+		{
+			text:       `CREATE TABLE "Sch".Tbl (`,
+			schemaName: `"Sch"`,
+			name:       "Tbl",
+			result:     `CREATE TABLE "Sch___tbl" (`,
+		},
+		{
+			text:       "CREATE TABLE s.tt (",
+			schemaName: "s",
+			name:       "t",
+			result:     "CREATE TABLE s.tt (",
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.text, func(t *testing.T) {
+			require.Equal(t, test.result, replaceSchemaAndName(test.text, test.schemaName, test.name))
+		})
+	}
+}
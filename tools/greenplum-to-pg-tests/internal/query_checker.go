@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/lib/pq"
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+	"github.com/ydb-platform/ydb-go-sdk/v3/query"
+)
+
+// QueryChecker check query compatibility with a database without really execute the query
+type QueryChecker interface {
+	// CheckQuery check the query, paramTypes is types of $N placeholders, nil if the query has no placeholders
+	CheckQuery(ctx context.Context, queryText string, paramTypes []string) error
+
+	// ExecQuery really execute the query, used for prepare the database
+	ExecQuery(ctx context.Context, queryText string) error
+
+	// Version return server version, used as key for cached check results
+	Version(ctx context.Context) (string, error)
+
+	Close(ctx context.Context) error
+}
+
+type YdbQueryChecker struct {
+	pool *YdbPool
+}
+
+func NewYdbQueryChecker(pool *YdbPool) *YdbQueryChecker {
+	return &YdbQueryChecker{pool: pool}
+}
+
+// Inflight return inflight queries of every driver in the pool
+func (c *YdbQueryChecker) Inflight() []int {
+	return c.pool.Inflight()
+}
+
+// CheckQuery ask ydb explain the query with postgres syntax, placeholders passed as typed parameters
+func (c *YdbQueryChecker) CheckQuery(ctx context.Context, queryText string, paramTypes []string) (err error) {
+	db := c.pool.Get()
+	defer func() { c.pool.Release(db, err) }()
+
+	// ydb name parameter $N of postgres syntax as $pN
+	params := ydb.ParamsBuilder()
+	for i, typeName := range paramTypes {
+		oid, sample := PgParamType(typeName)
+		params = params.Param(fmt.Sprintf("$p%v", i+1)).Pg().Value(oid, sample)
+	}
+
+	res, err := db.Query().Execute(
+		ctx,
+		queryText,
+		query.WithExecMode(query.ExecModeExplain),
+		query.WithSyntax(query.SyntaxPostgreSQL),
+		query.WithParameters(params.Build()),
+	)
+	if res != nil {
+		_ = res.Close(ctx)
+	}
+	return err
+}
+
+func (c *YdbQueryChecker) ExecQuery(ctx context.Context, queryText string) (err error) {
+	db := c.pool.Get()
+	defer func() { c.pool.Release(db, err) }()
+
+	res, err := db.Query().Execute(ctx, queryText, query.WithSyntax(query.SyntaxPostgreSQL))
+	if res != nil {
+		_ = res.Close(ctx)
+	}
+	return err
+}
+
+func (c *YdbQueryChecker) Version(ctx context.Context) (_ string, err error) {
+	db := c.pool.Get()
+	defer func() { c.pool.Release(db, err) }()
+
+	row, err := db.Query().ReadRow(ctx, "SELECT Version()")
+	if err != nil {
+		return "", fmt.Errorf("failed to read ydb version: %w", err)
+	}
+
+	var version string
+	if err = row.Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to scan ydb version: %w", err)
+	}
+	return version, nil
+}
+
+func (c *YdbQueryChecker) Close(ctx context.Context) error {
+	return c.pool.Close(ctx)
+}
+
+type PgCheckMode string
+
+const (
+	PgCheckModeExplain PgCheckMode = "explain"
+	PgCheckModePrepare PgCheckMode = "prepare"
+)
+
+// PgQueryChecker check queries by vanilla postgres server, used as reference for separate
+// ydb gaps from queries, broken on source database
+type PgQueryChecker struct {
+	db   *sql.DB
+	mode PgCheckMode
+}
+
+func OpenPgQueryChecker(ctx context.Context, connectionString string, mode PgCheckMode, maxConnections int) (*PgQueryChecker, error) {
+	switch mode {
+	case PgCheckModeExplain, PgCheckModePrepare:
+		// pass
+	default:
+		return nil, fmt.Errorf("unknown postgres check mode: %q", mode)
+	}
+
+	db, err := sql.Open("postgres", connectionString)
+	if err == nil {
+		err = db.PingContext(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	db.SetMaxOpenConns(maxConnections)
+	db.SetMaxIdleConns(maxConnections)
+
+	return &PgQueryChecker{
+		db:   db,
+		mode: mode,
+	}, nil
+}
+
+// CheckQuery parse the query by postgres server. Prepare mode is more universal: it works
+// for queries with $N placeholders and for DDL, explain mode check plan building too.
+// Queries with placeholders are prepared by PREPARE with explicit types in both modes.
+func (c *PgQueryChecker) CheckQuery(ctx context.Context, queryText string, paramTypes []string) error {
+	if len(paramTypes) > 0 {
+		return c.checkTypedQuery(ctx, queryText, paramTypes)
+	}
+
+	switch c.mode {
+	case PgCheckModeExplain:
+		rows, err := c.db.QueryContext(ctx, "EXPLAIN "+queryText)
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	case PgCheckModePrepare:
+		stmt, err := c.db.PrepareContext(ctx, queryText)
+		if err != nil {
+			return err
+		}
+		return stmt.Close()
+	default:
+		panic(fmt.Sprintf("unexpected postgres check mode: %q", c.mode))
+	}
+}
+
+// checkTypedQuery prepare the query with explicit types of parameters, explain mode explain execution
+// of the prepared statement with null parameters
+func (c *PgQueryChecker) checkTypedQuery(ctx context.Context, queryText string, paramTypes []string) error {
+	const statementName = "pg_queries_check"
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err = conn.ExecContext(ctx, fmt.Sprintf("PREPARE %v(%v) AS %v", statementName, strings.Join(paramTypes, ", "), queryText)); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(context.Background(), "DEALLOCATE "+statementName) }()
+
+	if c.mode == PgCheckModeExplain {
+		nulls := strings.TrimSuffix(strings.Repeat("NULL, ", len(paramTypes)), ", ")
+		rows, err := conn.QueryContext(ctx, fmt.Sprintf("EXPLAIN EXECUTE %v(%v)", statementName, nulls))
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	}
+	return nil
+}
+
+func (c *PgQueryChecker) ExecQuery(ctx context.Context, queryText string) error {
+	_, err := c.db.ExecContext(ctx, queryText)
+	return err
+}
+
+func (c *PgQueryChecker) Version(ctx context.Context) (string, error) {
+	var version string
+	if err := c.db.QueryRowContext(ctx, "SELECT version()").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to read postgres version: %w", err)
+	}
+	return version, nil
+}
+
+func (c *PgQueryChecker) Close(_ context.Context) error {
+	return c.db.Close()
+}
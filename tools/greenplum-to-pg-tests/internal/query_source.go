@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type pgStatStatementsReader struct {
+	io.Closer
+	csv         *csv.Reader
+	queryIndex  int
+	callsIndex  int
+	recordIndex int
+}
+
+// NewPgStatStatementsReader read csv export of pg_stat_statements with header line.
+// Every row is separate session with one query, calls column (if exists) used as query weight.
+func NewPgStatStatementsReader(reader io.ReadCloser) (SessionLogReader, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_statements header: %w", err)
+	}
+
+	res := &pgStatStatementsReader{
+		Closer:     reader,
+		csv:        csvReader,
+		queryIndex: -1,
+		callsIndex: -1,
+	}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "query":
+			res.queryIndex = i
+		case "calls":
+			res.callsIndex = i
+		}
+	}
+	if res.queryIndex < 0 {
+		return nil, fmt.Errorf("pg_stat_statements export has no query column: %q", header)
+	}
+	return res, nil
+}
+
+func (r *pgStatStatementsReader) Next() (SessionLogRecord, error) {
+	for {
+		line, err := r.csv.Read()
+		if err != nil {
+			return SessionLogRecord{}, err
+		}
+		if r.queryIndex >= len(line) || strings.TrimSpace(line[r.queryIndex]) == "" {
+			continue
+		}
+
+		var calls *int
+		if r.callsIndex >= 0 && r.callsIndex < len(line) {
+			value, err := strconv.Atoi(strings.TrimSpace(line[r.callsIndex]))
+			if err != nil {
+				return SessionLogRecord{}, fmt.Errorf("failed to parse calls %q: %w", line[r.callsIndex], err)
+			}
+			calls = &value
+		}
+
+		r.recordIndex++
+		return SessionLogRecord{
+			SessionID:          r.recordIndex,
+			Query:              line[r.queryIndex],
+			TransactionSuccess: true,
+			Calls:              calls,
+		}, nil
+	}
+}
+
+type sqlFilesReader struct {
+	files      []string
+	fileIndex  int
+	statements []string
+	position   int
+}
+
+// NewSqlFilesReader read queries from .sql file or from all .sql files of directory (recursive).
+// Every file is separate session, every statement of the file is separate transaction.
+func NewSqlFilesReader(path string) (SessionLogReader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return &sqlFilesReader{files: []string{path}}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(filePath), ".sql") {
+			files = append(files, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return &sqlFilesReader{files: files}, nil
+}
+
+func (r *sqlFilesReader) Next() (SessionLogRecord, error) {
+	for r.position >= len(r.statements) {
+		if r.fileIndex >= len(r.files) {
+			return SessionLogRecord{}, io.EOF
+		}
+		content, err := os.ReadFile(r.files[r.fileIndex])
+		if err != nil {
+			return SessionLogRecord{}, err
+		}
+		r.fileIndex++
+		r.statements = SplitStatements(string(content))
+		r.position = 0
+	}
+
+	record := SessionLogRecord{
+		SessionID:          r.fileIndex,
+		Query:              r.statements[r.position],
+		TransactionCount:   r.position,
+		TransactionSuccess: true,
+	}
+	r.position++
+	return record, nil
+}
+
+func (r *sqlFilesReader) Close() error {
+	return nil
+}
+
+// SplitStatements split sql script to statements by semicolons outside of strings, dollar quotes,
+// quoted identifiers, comments and parentheses. Empty and comments-only statements skipped.
+// Statement with unclosed parenthesis (truncated line for example) is split by every semicolon,
+// else it takes the rest of the script.
+func SplitStatements(text string) []string {
+	var res []string
+	var current []Token
+	flush := func() {
+		if slices.ContainsFunc(current, Token.IsMeaningful) {
+			res = append(res, strings.TrimSpace(tokensText(current)))
+		}
+		current = current[:0]
+	}
+
+	depth := 0
+	for _, token := range LexSQL(text) {
+		switch {
+		case token.Text == "(":
+			depth++
+		case token.Text == ")" && depth > 0:
+			depth--
+		case token.Text == ";" && depth == 0:
+			flush()
+			continue
+		}
+		current = append(current, token)
+	}
+
+	if depth > 0 && slices.ContainsFunc(current, func(token Token) bool { return token.Text == ";" }) {
+		log.Printf("Unclosed parenthesis in statement, split it by every semicolon: %.100q", strings.TrimSpace(tokensText(current)))
+		statement := slices.Clone(current)
+		current = current[:0]
+		for _, token := range statement {
+			if token.Text == ";" {
+				flush()
+				continue
+			}
+			current = append(current, token)
+		}
+	}
+	flush()
+	return res
+}
+
+func tokensText(tokens []Token) string {
+	var buf strings.Builder
+	for _, token := range tokens {
+		buf.WriteString(token.Text)
+	}
+	return buf.String()
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	table := []struct {
+		name       string
+		text       string
+		statements []string
+	}{
+		{
+			name:       "Simple",
+			text:       "SELECT 1;\nSELECT 2;;\n",
+			statements: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:       "UnclosedParenthesis",
+			text:       "SELECT f(1, 2;\nSELECT 2; SELECT (3)",
+			statements: []string{"SELECT f(1, 2", "SELECT 2", "SELECT (3)"},
+		},
+		{
+			name:       "Strings",
+			text:       `SELECT 'a;b', "c;d", E'\';'; SELECT 2`,
+			statements: []string{`SELECT 'a;b', "c;d", E'\';'`, "SELECT 2"},
+		},
+		{
+			name: "DollarQuotes",
+			text: "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;\nSELECT f()",
+			statements: []string{
+				"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql",
+				"SELECT f()",
+			},
+		},
+		{
+			name:       "Comments",
+			text:       "-- header; comment\n/* a; /* b; */ */\nSELECT 1; -- tail;\n",
+			statements: []string{"-- header; comment\n/* a; /* b; */ */\nSELECT 1"},
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.statements, SplitStatements(test.text))
+		})
+	}
+}
+
+func TestPgStatStatementsReader(t *testing.T) {
+	data := `userid,dbid,query,calls,total_time
+10,5,"SELECT * FROM t WHERE id = $1",42,1.5
+10,5,"",1,0.1
+10,5,"UPDATE t
+SET a = $1",3,0.7
+10,5,"SELECT 1",0,0
+`
+	reader, err := NewPgStatStatementsReader(io.NopCloser(strings.NewReader(data)))
+	require.NoError(t, err)
+
+	records := readAllRecords(t, reader)
+	calls := []int{42, 3, 0}
+	require.Equal(t, []SessionLogRecord{
+		{SessionID: 1, Query: "SELECT * FROM t WHERE id = $1", TransactionSuccess: true, Calls: &calls[0]},
+		{SessionID: 2, Query: "UPDATE t\nSET a = $1", TransactionSuccess: true, Calls: &calls[1]},
+		{SessionID: 3, Query: "SELECT 1", TransactionSuccess: true, Calls: &calls[2]},
+	}, records)
+	require.Equal(t, 42, records[0].GetCalls())
+	require.Equal(t, 0, records[2].GetCalls())
+	require.Equal(t, 1, SessionLogRecord{}.GetCalls())
+
+	_, err = NewPgStatStatementsReader(io.NopCloser(strings.NewReader("userid,calls\n1,2\n")))
+	require.Error(t, err)
+}
+
+func TestSqlFilesReader(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.sql"), []byte("SELECT 2; SELECT 3;"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.sql"), []byte("-- only comment;\nSELECT 1"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "c.sql"), []byte("SELECT 4"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("SELECT 5"), 0o644))
+
+	reader, err := NewSqlFilesReader(dir)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	records := readAllRecords(t, reader)
+	require.Equal(t, []SessionLogRecord{
+		{SessionID: 1, Query: "-- only comment;\nSELECT 1", TransactionSuccess: true},
+		{SessionID: 2, Query: "SELECT 2", TransactionSuccess: true},
+		{SessionID: 2, Query: "SELECT 3", TransactionCount: 1, TransactionSuccess: true},
+		{SessionID: 3, Query: "SELECT 4", TransactionSuccess: true},
+	}, records)
+}
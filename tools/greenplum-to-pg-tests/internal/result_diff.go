@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDiffTooManyRows      = errors.New("too many rows for compare")
+	ErrDiffReadOnlyRejected = errors.New("read-only transaction rejected")
+)
+
+// DiffExecutionError is error of the query on ydb, when the query executed successfully on reference postgres
+type DiffExecutionError struct {
+	Err error
+}
+
+func (e *DiffExecutionError) Error() string {
+	return fmt.Sprintf("ydb execution failed: %v", e.Err)
+}
+
+func (e *DiffExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// ResultDiffer execute read-only queries on reference postgres and on ydb postgres endpoint and compare results
+type ResultDiffer struct {
+	reference *sql.DB
+	ydb       *sql.DB
+	maxRows   int
+}
+
+func OpenResultDiffer(ctx context.Context, referenceConnectionString, ydbConnectionString string, maxConnections, maxRows int) (*ResultDiffer, error) {
+	reference, err := openPgDB(ctx, referenceConnectionString, maxConnections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to reference postgres: %w", err)
+	}
+	ydbDB, err := openPgDB(ctx, ydbConnectionString, maxConnections)
+	if err != nil {
+		_ = reference.Close()
+		return nil, fmt.Errorf("failed to connect to ydb postgres endpoint: %w", err)
+	}
+
+	return &ResultDiffer{
+		reference: reference,
+		ydb:       ydbDB,
+		maxRows:   maxRows,
+	}, nil
+}
+
+func openPgDB(ctx context.Context, connectionString string, maxConnections int) (*sql.DB, error) {
+	db, err := sql.Open("postgres", connectionString)
+	if err == nil {
+		err = db.PingContext(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(maxConnections)
+	db.SetMaxIdleConns(maxConnections)
+	return db, nil
+}
+
+// Compare return empty mismatch if both servers return same result.
+// referenceQuery is original text of the query for reference postgres with original schema,
+// ydbQuery is the query prepared for ydb.
+// DiffExecutionError returned if the query failed on ydb only, other errors returned if the query can't be compared,
+// for example if it failed on reference server.
+func (d *ResultDiffer) Compare(ctx context.Context, referenceQuery, ydbQuery string) (mismatch string, err error) {
+	referenceResult, err := d.readResult(ctx, d.reference, referenceQuery)
+	if err != nil {
+		return "", fmt.Errorf("reference postgres: %w", err)
+	}
+
+	ydbResult, err := d.readResult(ctx, d.ydb, ydbQuery)
+	if err != nil {
+		if errors.Is(err, ErrDiffTooManyRows) || errors.Is(err, ErrDiffReadOnlyRejected) {
+			return "", fmt.Errorf("ydb: %w", err)
+		}
+		return "", &DiffExecutionError{Err: err}
+	}
+
+	return referenceResult.diff(ydbResult, !HasTopLevelOrderBy(ydbQuery)), nil
+}
+
+func (d *ResultDiffer) Close() error {
+	return errors.Join(d.reference.Close(), d.ydb.Close())
+}
+
+type queryResult struct {
+	columns []string
+	types   []string
+	rows    []string
+}
+
+// readResult execute the query in read-only transaction, which always rolled back,
+// so queries with side effects (nextval, advisory locks, writable CTE, ...) can't change the database
+func (d *ResultDiffer) readResult(ctx context.Context, db *sql.DB, queryText string) (*queryResult, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiffReadOnlyRejected, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, queryText)
+	if err != nil {
+		if isReadOnlyViolation(err) {
+			return nil, fmt.Errorf("%w: %w", ErrDiffReadOnlyRejected, err)
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res queryResult
+	if res.columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range columnTypes {
+		res.types = append(res.types, t.DatabaseTypeName())
+	}
+
+	values := make([]any, len(res.columns))
+	pointers := make([]any, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if d.maxRows > 0 && len(res.rows) >= d.maxRows {
+			return nil, ErrDiffTooManyRows
+		}
+		if err = rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		res.rows = append(res.rows, formatRow(values))
+	}
+
+	return &res, rows.Err()
+}
+
+// isReadOnlyViolation detect query, which tried to change data in read-only transaction
+func isReadOnlyViolation(err error) bool {
+	var pgErr *pq.Error
+	return errors.As(err, &pgErr) && pgErr.Code.Name() == "read_only_sql_transaction"
+}
+
+func (r *queryResult) diff(other *queryResult, ignoreOrder bool) string {
+	switch {
+	case !slices.Equal(r.columns, other.columns):
+		return "column names differ"
+	case !slices.Equal(r.types, other.types):
+		return "column types differ"
+	case len(r.rows) != len(other.rows):
+		return "row count differ"
+	}
+
+	rows, otherRows := r.rows, other.rows
+	if ignoreOrder {
+		rows, otherRows = slices.Clone(rows), slices.Clone(otherRows)
+		slices.Sort(rows)
+		slices.Sort(otherRows)
+	}
+	if !slices.Equal(rows, otherRows) {
+		return "row values differ"
+	}
+	return ""
+}
+
+func formatRow(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+			parts[i] = "NULL"
+		case []byte:
+			parts[i] = fmt.Sprintf("%q", v)
+		case string:
+			parts[i] = fmt.Sprintf("%q", v)
+		case time.Time:
+			parts[i] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+var (
+	readOnlyQueryRegexp = regexp.MustCompile(`(?is)^[\s(]*(SELECT|WITH|VALUES|TABLE)\b`)
+	modifyQueryRegexp   = regexp.MustCompile(`(?is)\b(INSERT|UPDATE|DELETE|INTO|MERGE|FOR\s+(NO\s+KEY\s+)?UPDATE|FOR\s+(KEY\s+)?SHARE)\b`)
+)
+
+// IsReadOnlyQuery detect queries, which look like read-only. It is a prefilter only:
+// functions with side effects are stopped by read-only transaction in ResultDiffer.
+func IsReadOnlyQuery(queryText string) bool {
+	return readOnlyQueryRegexp.MatchString(queryText) && !modifyQueryRegexp.MatchString(queryText)
+}
+
+// HasTopLevelOrderBy check ORDER BY outside of parentheses, order of subqueries doesn't matter for result.
+// Parentheses and words in strings and comments are ignored.
+func HasTopLevelOrderBy(queryText string) bool {
+	tokens := LexSQL(queryText)
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.Kind == TokenPunctuation && token.Text == "(":
+			depth++
+		case token.Kind == TokenPunctuation && token.Text == ")":
+			depth--
+		case depth == 0 && token.IsKeyword("ORDER"):
+			if next := nextMeaningful(tokens, i+1); next >= 0 && tokens[next].IsKeyword("BY") {
+				return true
+			}
+		}
+	}
+	return false
+}
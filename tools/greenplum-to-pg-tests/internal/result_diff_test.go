@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultDiff(t *testing.T) {
+	base := &queryResult{
+		columns: []string{"a", "b"},
+		types:   []string{"INT4", "TEXT"},
+		rows:    []string{`1, "x"`, `2, "y"`},
+	}
+
+	table := []struct {
+		name        string
+		other       *queryResult
+		ignoreOrder bool
+		mismatch    string
+	}{
+		{
+			name:  "Same",
+			other: &queryResult{columns: []string{"a", "b"}, types: []string{"INT4", "TEXT"}, rows: []string{`1, "x"`, `2, "y"`}},
+		},
+		{
+			name:     "ColumnNames",
+			other:    &queryResult{columns: []string{"a", "c"}, types: []string{"INT4", "TEXT"}, rows: []string{`1, "x"`, `2, "y"`}},
+			mismatch: "column names differ",
+		},
+		{
+			name:     "ColumnTypes",
+			other:    &queryResult{columns: []string{"a", "b"}, types: []string{"INT8", "TEXT"}, rows: []string{`1, "x"`, `2, "y"`}},
+			mismatch: "column types differ",
+		},
+		{
+			name:     "RowCount",
+			other:    &queryResult{columns: []string{"a", "b"}, types: []string{"INT4", "TEXT"}, rows: []string{`1, "x"`}},
+			mismatch: "row count differ",
+		},
+		{
+			name:     "OrderMatters",
+			other:    &queryResult{columns: []string{"a", "b"}, types: []string{"INT4", "TEXT"}, rows: []string{`2, "y"`, `1, "x"`}},
+			mismatch: "row values differ",
+		},
+		{
+			name:        "OrderIgnored",
+			other:       &queryResult{columns: []string{"a", "b"}, types: []string{"INT4", "TEXT"}, rows: []string{`2, "y"`, `1, "x"`}},
+			ignoreOrder: true,
+		},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.mismatch, base.diff(test.other, test.ignoreOrder))
+		})
+	}
+}
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	require.True(t, IsReadOnlyQuery("SELECT * FROM t"))
+	require.True(t, IsReadOnlyQuery("  (select 1) union (select 2)"))
+	require.True(t, IsReadOnlyQuery("WITH a AS (SELECT 1) SELECT * FROM a"))
+	require.False(t, IsReadOnlyQuery("WITH a AS (DELETE FROM t RETURNING *) SELECT * FROM a"))
+	require.False(t, IsReadOnlyQuery("SELECT * INTO t2 FROM t"))
+	require.False(t, IsReadOnlyQuery("SELECT * FROM t FOR UPDATE"))
+	require.False(t, IsReadOnlyQuery("UPDATE t SET a=1"))
+}
+
+func TestHasTopLevelOrderBy(t *testing.T) {
+	require.True(t, HasTopLevelOrderBy("SELECT * FROM t ORDER BY a"))
+	require.True(t, HasTopLevelOrderBy("SELECT * FROM (SELECT 1) t order\n  by 1"))
+	require.False(t, HasTopLevelOrderBy("SELECT * FROM (SELECT a FROM t ORDER BY a LIMIT 3) t"))
+	require.True(t, HasTopLevelOrderBy("SELECT ')' FROM t /* ( */ ORDER BY 1"))
+	require.False(t, HasTopLevelOrderBy("SELECT '(' FROM (SELECT a FROM t ORDER BY a) t -- )"))
+	require.False(t, HasTopLevelOrderBy("SELECT 'ORDER BY a' FROM t -- order by b"))
+}
+
+func TestIsReadOnlyViolation(t *testing.T) {
+	require.True(t, isReadOnlyViolation(fmt.Errorf("wrapped: %w", &pq.Error{Code: "25006"})))
+	require.False(t, isReadOnlyViolation(&pq.Error{Code: "42601"}))
+	require.False(t, isReadOnlyViolation(errors.New("other")))
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "time"
+
 type SessionLogRecord struct {
 	ProcessID          int    `json:"pid"`
 	SessionID          int    `json:"sess_id"`
@@ -7,21 +9,33 @@ type SessionLogRecord struct {
 	Query              string `json:"query"`
 	TransactionCount   int    `json:"transaction_count"`
 	TransactionSuccess bool   `json:"transaction_success"`
+	Calls              *int   `json:"calls,omitempty"` // executions count of the query, nil mean 1
+}
+
+// GetCalls return executions count of the query, 0 if the query wasn't executed and must be skipped
+func (r SessionLogRecord) GetCalls() int {
+	if r.Calls == nil {
+		return 1
+	}
+	return max(*r.Calls, 0)
 }
 
 type Session struct {
 	ID           string
-	Success      bool
+	Success      bool // filled by replay
 	Transactions []Transaction
 }
 
 type Transaction struct {
-	Number  int
-	Success bool
-	Queries []Query
+	Number     int
+	Success    bool          // filled by replay
+	Latency    time.Duration // filled by replay
+	LogSuccess bool          // transaction result from source log
+	Queries    []Query
 }
 
 type Query struct {
 	Number int
 	Text   string
+	Calls  int
 }
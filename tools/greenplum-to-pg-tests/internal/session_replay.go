@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ydb-platform/ydb-go-sdk/v3/query"
+)
+
+// SessionReplayer execute transactions of the session in order on one database session
+// with real transactions instead of check separated queries
+type SessionReplayer interface {
+	// ReplaySession fill Success and Latency fields of the session and the transactions
+	// and return error for every failed transaction, nil for success transactions.
+	// beforeQuery is called before send every query, it is used for limit rate of queries.
+	ReplaySession(ctx context.Context, session *Session, beforeQuery func(ctx context.Context)) []error
+}
+
+// ReplayError contains query, failed on replay transaction
+type ReplayError struct {
+	Query string
+	Err   error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("failed to execute query %q: %v", e.Query, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+var transactionControlRegexp = regexp.MustCompile(`(?is)^\s*(BEGIN|START\s+TRANSACTION|COMMIT|END|ROLLBACK|ABORT)(\s+(WORK|TRANSACTION))?\s*;?\s*$`)
+
+// IsTransactionControl detect queries, which manage transactions. Replayer manage transactions itself
+// and skip the queries from log.
+func IsTransactionControl(queryText string) bool {
+	return transactionControlRegexp.MatchString(queryText)
+}
+
+func (c *YdbQueryChecker) ReplaySession(ctx context.Context, session *Session, beforeQuery func(ctx context.Context)) []error {
+	db := c.pool.Get()
+
+	txErrors := make([]error, len(session.Transactions))
+	err := db.Query().Do(ctx, func(ctx context.Context, s query.Session) error {
+		for i := range session.Transactions {
+			start := time.Now()
+			txErrors[i] = replayYdbTransaction(ctx, s, session.Transactions[i], beforeQuery)
+			session.Transactions[i].Latency = time.Since(start)
+		}
+		return nil
+	})
+	c.pool.Release(db, err)
+	if err != nil {
+		for i := range txErrors {
+			txErrors[i] = err
+		}
+	}
+
+	fillReplaySuccess(session, txErrors)
+	return txErrors
+}
+
+func replayYdbTransaction(ctx context.Context, s query.Session, transaction Transaction, beforeQuery func(ctx context.Context)) error {
+	tx, err := s.Begin(ctx, query.TxSettings(query.WithSerializableReadWrite()))
+	if err != nil {
+		return err
+	}
+
+	for _, q := range transaction.Queries {
+		if IsTransactionControl(q.Text) {
+			continue
+		}
+
+		beforeQuery(ctx)
+		res, err := tx.Execute(ctx, q.Text, query.WithSyntax(query.SyntaxPostgreSQL))
+		if res != nil {
+			_ = res.Close(ctx)
+		}
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return &ReplayError{Query: q.Text, Err: err}
+		}
+	}
+
+	if transaction.LogSuccess {
+		return tx.CommitTx(ctx)
+	}
+	return tx.Rollback(ctx)
+}
+
+func (c *PgQueryChecker) ReplaySession(ctx context.Context, session *Session, beforeQuery func(ctx context.Context)) []error {
+	txErrors := make([]error, len(session.Transactions))
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		for i := range txErrors {
+			txErrors[i] = err
+		}
+		fillReplaySuccess(session, txErrors)
+		return txErrors
+	}
+	defer func() { _ = conn.Close() }()
+
+	for i := range session.Transactions {
+		start := time.Now()
+		txErrors[i] = replayPgTransaction(ctx, conn, session.Transactions[i], beforeQuery)
+		session.Transactions[i].Latency = time.Since(start)
+	}
+
+	fillReplaySuccess(session, txErrors)
+	return txErrors
+}
+
+func replayPgTransaction(ctx context.Context, conn *sql.Conn, transaction Transaction, beforeQuery func(ctx context.Context)) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range transaction.Queries {
+		if IsTransactionControl(q.Text) {
+			continue
+		}
+
+		beforeQuery(ctx)
+		if _, err = tx.ExecContext(ctx, q.Text); err != nil {
+			_ = tx.Rollback()
+			return &ReplayError{Query: q.Text, Err: err}
+		}
+	}
+
+	if transaction.LogSuccess {
+		return tx.Commit()
+	}
+	return tx.Rollback()
+}
+
+func fillReplaySuccess(session *Session, txErrors []error) {
+	session.Success = true
+	for i := range session.Transactions {
+		session.Transactions[i].Success = txErrors[i] == nil
+		if txErrors[i] != nil {
+			session.Success = false
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package internal
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// approximate memory of record in sort buffer without query text
+const sortRecordOverhead = 128
+
+type sortRecord struct {
+	Seq    int64 // position in input, for keep first of duplicated records
+	Record SessionLogRecord
+}
+
+func compareSortRecords(a, b sortRecord) int {
+	return cmp.Or(
+		compareRecordKeys(a.Record, b.Record),
+		cmp.Compare(a.Seq, b.Seq),
+	)
+}
+
+func compareRecordKeys(a, b SessionLogRecord) int {
+	return cmp.Or(
+		cmp.Compare(a.ProcessID, b.ProcessID),
+		cmp.Compare(a.SessionID, b.SessionID),
+		cmp.Compare(a.TransactionCount, b.TransactionCount),
+		cmp.Compare(a.QueryCount, b.QueryCount),
+	)
+}
+
+// defaultMaxMergeRuns is max count of run files, opened at once by merge
+const defaultMaxMergeRuns = 64
+
+// SessionSorter sort log records by pid/session/transaction/query with bounded memory.
+// Records buffered in memory until memoryLimit, then sorted buffer spilled to run file in temp dir.
+// Run files merged by batches of maxMergeRuns, sessions merged from rest run files and rest of buffer.
+type SessionSorter struct {
+	tempDirParent string
+	tempDir       string
+	memoryLimit   int64
+	maxMergeRuns  int
+
+	buffer     []sortRecord
+	bufferSize int64
+	runs       []string
+	runCounter int
+	seq        int64
+	duplicates int64
+}
+
+// NewSessionSorter create sorter, tempDir is parent for temp files (os.TempDir if empty),
+// memoryLimit is approximate size of buffered records in bytes, 0 mean unlimited
+func NewSessionSorter(tempDir string, memoryLimit int64) *SessionSorter {
+	return &SessionSorter{
+		tempDirParent: tempDir,
+		memoryLimit:   memoryLimit,
+		maxMergeRuns:  defaultMaxMergeRuns,
+	}
+}
+
+func (s *SessionSorter) Add(record SessionLogRecord) error {
+	s.buffer = append(s.buffer, sortRecord{Seq: s.seq, Record: record})
+	s.seq++
+	s.bufferSize += sortRecordOverhead + int64(len(record.Query))
+
+	if s.memoryLimit > 0 && s.bufferSize >= s.memoryLimit {
+		return s.spill()
+	}
+	return nil
+}
+
+// Count return count of added records, duplicated records excluded after Sessions
+func (s *SessionSorter) Count() int {
+	return int(s.seq - s.duplicates)
+}
+
+func (s *SessionSorter) spill() error {
+	slices.SortFunc(s.buffer, compareSortRecords)
+
+	run, err := s.createRun()
+	if err != nil {
+		return err
+	}
+	for i := range s.buffer {
+		if err = run.write(&s.buffer[i]); err != nil {
+			return err
+		}
+	}
+	if err = run.close(); err != nil {
+		return err
+	}
+
+	s.runs = append(s.runs, run.path)
+	s.buffer = s.buffer[:0]
+	s.bufferSize = 0
+	return nil
+}
+
+// Sessions finish adding records and return iterator over sorted sessions.
+// Duplicated records (same pid/session/transaction/query) skipped, first record in input wins.
+func (s *SessionSorter) Sessions() (_ *SessionIterator, err error) {
+	defer func() {
+		if err != nil && s.tempDir != "" {
+			_ = os.RemoveAll(s.tempDir)
+			s.tempDir = ""
+		}
+	}()
+
+	slices.SortFunc(s.buffer, compareSortRecords)
+
+	// buffer is one more source of final merge
+	for len(s.runs)+1 > s.maxMergeRuns {
+		if err = s.mergeRunsBatches(); err != nil {
+			return nil, err
+		}
+	}
+
+	// count records without duplicates for progress, extra read of runs without decode to sessions
+	counter, err := openRecordMerger(s.buffer, s.runs)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err = counter.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = counter.Close()
+			return nil, err
+		}
+	}
+	s.duplicates += counter.duplicates
+	if err = counter.Close(); err != nil {
+		return nil, err
+	}
+
+	merger, err := openRecordMerger(s.buffer, s.runs)
+	if err != nil {
+		return nil, err
+	}
+	merger.onDuplicate = func(record SessionLogRecord) {
+		log.Printf("duplicated record: %v/%v/%v\n", record.SessionID, record.TransactionCount, record.QueryCount)
+	}
+	iterator := &SessionIterator{tempDir: s.tempDir, merger: merger}
+	s.buffer = nil
+	s.runs = nil
+	s.tempDir = ""
+	return iterator, nil
+}
+
+// mergeRunsBatches merge every maxMergeRuns run files to one, duplicated records removed
+func (s *SessionSorter) mergeRunsBatches() error {
+	var merged []string
+	for start := 0; start < len(s.runs); start += s.maxMergeRuns {
+		batch := s.runs[start:min(start+s.maxMergeRuns, len(s.runs))]
+		if len(batch) == 1 {
+			merged = append(merged, batch[0])
+			continue
+		}
+
+		path, err := s.mergeRuns(batch)
+		if err != nil {
+			return err
+		}
+		merged = append(merged, path)
+	}
+	s.runs = merged
+	return nil
+}
+
+func (s *SessionSorter) mergeRuns(runs []string) (string, error) {
+	merger, err := openRecordMerger(nil, runs)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = merger.Close() }()
+
+	run, err := s.createRun()
+	if err != nil {
+		return "", err
+	}
+	for {
+		record, err := merger.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = run.file.Close()
+			return "", err
+		}
+		if err = run.write(&record); err != nil {
+			return "", err
+		}
+	}
+	if err = run.close(); err != nil {
+		return "", err
+	}
+	s.duplicates += merger.duplicates
+
+	for _, path := range runs {
+		if err = os.Remove(path); err != nil {
+			return "", fmt.Errorf("failed to remove merged sort run: %w", err)
+		}
+	}
+	return run.path, nil
+}
+
+// runWriter write sorted records to run file
+type runWriter struct {
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *gob.Encoder
+}
+
+func (s *SessionSorter) createRun() (*runWriter, error) {
+	if s.tempDir == "" {
+		dir, err := os.MkdirTemp(s.tempDirParent, "session-sort-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp dir for sort: %w", err)
+		}
+		s.tempDir = dir
+	}
+
+	runPath := filepath.Join(s.tempDir, fmt.Sprintf("run-%06d", s.runCounter))
+	s.runCounter++
+	file, err := os.Create(runPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sort run file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	return &runWriter{path: runPath, file: file, writer: writer, encoder: gob.NewEncoder(writer)}, nil
+}
+
+func (w *runWriter) write(record *sortRecord) error {
+	if err := w.encoder.Encode(record); err != nil {
+		_ = w.file.Close()
+		return fmt.Errorf("failed to write sort run %q: %w", w.path, err)
+	}
+	return nil
+}
+
+func (w *runWriter) close() error {
+	if err := w.writer.Flush(); err != nil {
+		_ = w.file.Close()
+		return fmt.Errorf("failed to write sort run %q: %w", w.path, err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close sort run %q: %w", w.path, err)
+	}
+	return nil
+}
+
+type sortSource interface {
+	next() (sortRecord, error)
+}
+
+type sliceSortSource struct {
+	records []sortRecord
+}
+
+func (s *sliceSortSource) next() (sortRecord, error) {
+	if len(s.records) == 0 {
+		return sortRecord{}, io.EOF
+	}
+	res := s.records[0]
+	s.records = s.records[1:]
+	return res, nil
+}
+
+type fileSortSource struct {
+	decoder *gob.Decoder
+}
+
+func (s *fileSortSource) next() (sortRecord, error) {
+	var res sortRecord
+	err := s.decoder.Decode(&res)
+	return res, err
+}
+
+type mergeItem struct {
+	record sortRecord
+	source sortSource
+}
+
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return compareSortRecords(h[i].record, h[j].record) < 0 }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)        { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// recordMerger merge sorted records from buffer and run files, duplicated records skipped
+type recordMerger struct {
+	files       []*os.File
+	merge       mergeHeap
+	last        *SessionLogRecord
+	duplicates  int64
+	onDuplicate func(record SessionLogRecord)
+}
+
+func openRecordMerger(records []sortRecord, runs []string) (*recordMerger, error) {
+	res := &recordMerger{}
+	sources := []sortSource{&sliceSortSource{records: records}}
+	for _, runPath := range runs {
+		file, err := os.Open(runPath)
+		if err != nil {
+			_ = res.Close()
+			return nil, fmt.Errorf("failed to open sort run: %w", err)
+		}
+		res.files = append(res.files, file)
+		sources = append(sources, &fileSortSource{decoder: gob.NewDecoder(bufio.NewReader(file))})
+	}
+
+	for _, source := range sources {
+		item, err := source.next()
+		if errors.Is(err, io.EOF) {
+			continue
+		}
+		if err != nil {
+			_ = res.Close()
+			return nil, err
+		}
+		res.merge = append(res.merge, mergeItem{record: item, source: source})
+	}
+	heap.Init(&res.merge)
+	return res, nil
+}
+
+// next return next record from merge without duplicates
+func (m *recordMerger) next() (sortRecord, error) {
+	for m.merge.Len() > 0 {
+		item := m.merge[0]
+		next, err := item.source.next()
+		switch {
+		case errors.Is(err, io.EOF):
+			heap.Pop(&m.merge)
+		case err != nil:
+			return sortRecord{}, err
+		default:
+			m.merge[0].record = next
+			heap.Fix(&m.merge, 0)
+		}
+
+		record := item.record.Record
+		if m.last != nil && compareRecordKeys(*m.last, record) == 0 {
+			m.duplicates++
+			if m.onDuplicate != nil {
+				m.onDuplicate(record)
+			}
+			continue
+		}
+		m.last = &record
+		return item.record, nil
+	}
+	return sortRecord{}, io.EOF
+}
+
+func (m *recordMerger) Close() error {
+	var errs []error
+	for _, file := range m.files {
+		errs = append(errs, file.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// SessionIterator return sorted sessions one by one, return io.EOF after last session
+type SessionIterator struct {
+	tempDir string
+	merger  *recordMerger
+
+	pending *SessionLogRecord
+}
+
+func (it *SessionIterator) nextRecord() (SessionLogRecord, error) {
+	item, err := it.merger.next()
+	return item.Record, err
+}
+
+func (it *SessionIterator) Next() (Session, error) {
+	first := it.pending
+	it.pending = nil
+	if first == nil {
+		record, err := it.nextRecord()
+		if err != nil {
+			return Session{}, err
+		}
+		first = &record
+	}
+
+	session := Session{ID: fmt.Sprintf("%v-%v", first.ProcessID, first.SessionID)}
+	record := *first
+	for {
+		transactions := session.Transactions
+		if len(transactions) == 0 || transactions[len(transactions)-1].Number != record.TransactionCount {
+			session.Transactions = append(session.Transactions, Transaction{
+				Number:     record.TransactionCount,
+				LogSuccess: true,
+			})
+		}
+		transaction := &session.Transactions[len(session.Transactions)-1]
+		transaction.Queries = append(transaction.Queries, Query{
+			Number: record.QueryCount,
+			Text:   record.Query,
+			Calls:  record.GetCalls(),
+		})
+		if !record.TransactionSuccess {
+			transaction.LogSuccess = false
+		}
+
+		next, err := it.nextRecord()
+		if errors.Is(err, io.EOF) {
+			return session, nil
+		}
+		if err != nil {
+			return Session{}, err
+		}
+		if next.ProcessID != first.ProcessID || next.SessionID != first.SessionID {
+			it.pending = &next
+			return session, nil
+		}
+		record = next
+	}
+}
+
+// Close close and remove temp files
+func (it *SessionIterator) Close() error {
+	errs := []error{it.merger.Close()}
+	if it.tempDir != "" {
+		errs = append(errs, os.RemoveAll(it.tempDir))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionSorter(t *testing.T) {
+	calls := 5
+	records := []SessionLogRecord{
+		{ProcessID: 2, SessionID: 1, TransactionCount: 0, QueryCount: 0, Query: "SELECT 21", TransactionSuccess: true},
+		{ProcessID: 1, SessionID: 1, TransactionCount: 1, QueryCount: 0, Query: "SELECT 3", TransactionSuccess: false},
+		{ProcessID: 1, SessionID: 1, TransactionCount: 0, QueryCount: 1, Query: "SELECT 2", TransactionSuccess: true},
+		{ProcessID: 1, SessionID: 1, TransactionCount: 0, QueryCount: 0, Query: "SELECT 1", TransactionSuccess: true},
+		{ProcessID: 1, SessionID: 1, TransactionCount: 0, QueryCount: 1, Query: "duplicate", TransactionSuccess: true},
+		{ProcessID: 1, SessionID: 2, TransactionCount: 0, QueryCount: 0, Query: "SELECT 4", TransactionSuccess: true, Calls: &calls},
+	}
+	expected := []Session{
+		{ID: "1-1", Transactions: []Transaction{
+			{Number: 0, LogSuccess: true, Queries: []Query{{Number: 0, Text: "SELECT 1", Calls: 1}, {Number: 1, Text: "SELECT 2", Calls: 1}}},
+			{Number: 1, LogSuccess: false, Queries: []Query{{Number: 0, Text: "SELECT 3", Calls: 1}}},
+		}},
+		{ID: "1-2", Transactions: []Transaction{
+			{Number: 0, LogSuccess: true, Queries: []Query{{Number: 0, Text: "SELECT 4", Calls: 5}}},
+		}},
+		{ID: "2-1", Transactions: []Transaction{
+			{Number: 0, LogSuccess: true, Queries: []Query{{Number: 0, Text: "SELECT 21", Calls: 1}}},
+		}},
+	}
+
+	table := []struct {
+		name         string
+		memoryLimit  int64
+		maxMergeRuns int
+	}{
+		{name: "InMemory", memoryLimit: 0},
+		{name: "SpillEveryRecord", memoryLimit: 1},
+		{name: "SpillEveryTwoRecords", memoryLimit: 2 * (sortRecordOverhead + 9)},
+		{name: "MergeRunsByTwo", memoryLimit: 1, maxMergeRuns: 2},
+		{name: "MergeRunsByThree", memoryLimit: 1, maxMergeRuns: 3},
+	}
+
+	for _, test := range table {
+		t.Run(test.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			sorter := NewSessionSorter(tempDir, test.memoryLimit)
+			if test.maxMergeRuns > 0 {
+				sorter.maxMergeRuns = test.maxMergeRuns
+			}
+			for _, record := range records {
+				require.NoError(t, sorter.Add(record))
+			}
+			require.Equal(t, len(records), sorter.Count())
+
+			iterator, err := sorter.Sessions()
+			require.NoError(t, err)
+			require.Equal(t, len(records)-1, sorter.Count())
+			if test.maxMergeRuns > 0 {
+				// buffer is one more source of merge
+				require.LessOrEqual(t, len(iterator.merger.files)+1, test.maxMergeRuns)
+			}
+
+			var sessions []Session
+			for {
+				session, err := iterator.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				require.NoError(t, err)
+				sessions = append(sessions, session)
+			}
+			require.Equal(t, expected, sessions)
+
+			require.NoError(t, iterator.Close())
+			entries, err := os.ReadDir(tempDir)
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TokenKind int
+
+const (
+	TokenWhitespace TokenKind = iota
+	TokenComment
+	TokenString
+	TokenNumber
+	TokenIdentifier
+	TokenQuotedIdentifier
+	TokenParam
+	TokenOperator
+	TokenPunctuation
+)
+
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// IsKeyword check if token is unquoted word, equal to keyword without case
+func (t Token) IsKeyword(keyword string) bool {
+	return t.Kind == TokenIdentifier && strings.EqualFold(t.Text, keyword)
+}
+
+// IsMeaningful return false for tokens, which doesn't affect query: whitespaces and comments
+func (t Token) IsMeaningful() bool {
+	return t.Kind != TokenWhitespace && t.Kind != TokenComment
+}
+
+const operatorChars = "+-*/<>=~!@#%^&|`?"
+
+// LexSQL split postgres query text to tokens. Concatenation of the tokens text is equal to the query.
+// Unterminated quotes and comments are consumed until the end of the text.
+func LexSQL(text string) []Token {
+	var res []Token
+	for len(text) > 0 {
+		kind, size := nextToken(text)
+		res = append(res, Token{Kind: kind, Text: text[:size]})
+		text = text[size:]
+	}
+	return res
+}
+
+func nextToken(text string) (TokenKind, int) {
+	r, runeSize := utf8.DecodeRuneInString(text)
+	switch {
+	case unicode.IsSpace(r):
+		return TokenWhitespace, len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+	case strings.HasPrefix(text, "--"):
+		if pos := strings.IndexByte(text, '\n'); pos >= 0 {
+			return TokenComment, pos
+		}
+		return TokenComment, len(text)
+	case strings.HasPrefix(text, "/*"):
+		return TokenComment, blockCommentLen(text)
+	case r == '\'':
+		return TokenString, quotedLen(text, 0, '\'', false)
+	case (r == 'E' || r == 'e') && strings.HasPrefix(text[1:], "'"):
+		return TokenString, quotedLen(text, 1, '\'', true)
+	case strings.ContainsRune("BbXxNn", r) && strings.HasPrefix(text[1:], "'"):
+		return TokenString, quotedLen(text, 1, '\'', false)
+	case (r == 'U' || r == 'u') && strings.HasPrefix(text[1:], "&'"):
+		return TokenString, quotedLen(text, 2, '\'', false)
+	case (r == 'U' || r == 'u') && strings.HasPrefix(text[1:], "&\""):
+		return TokenQuotedIdentifier, quotedLen(text, 2, '"', false)
+	case r == '"':
+		return TokenQuotedIdentifier, quotedLen(text, 0, '"', false)
+	case r == '$':
+		if size := paramLen(text); size > 0 {
+			return TokenParam, size
+		}
+		if size := dollarQuotedLen(text); size > 0 {
+			return TokenString, size
+		}
+		return TokenOperator, 1
+	case isDigit(r) || r == '.' && len(text) > 1 && isDigit(rune(text[1])):
+		return TokenNumber, numberLen(text)
+	case isIdentifierStart(r):
+		size := runeSize
+		for size < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[size:])
+			if !isIdentifierStart(next) && !isDigit(next) && next != '$' {
+				break
+			}
+			size += nextSize
+		}
+		return TokenIdentifier, size
+	case r == ':' && strings.HasPrefix(text, "::"):
+		return TokenOperator, 2
+	case strings.ContainsRune(operatorChars, r):
+		size := 1
+		for size < len(text) && strings.IndexByte(operatorChars, text[size]) >= 0 &&
+			!strings.HasPrefix(text[size:], "--") && !strings.HasPrefix(text[size:], "/*") {
+			size++
+		}
+		// same as postgres: operator can't end with + or - if it doesn't contain special chars, for parse a>=-1
+		if !strings.ContainsAny(text[:size], "~!@#%^&|`?") {
+			for size > 1 && (text[size-1] == '+' || text[size-1] == '-') {
+				size--
+			}
+		}
+		return TokenOperator, size
+	default:
+		return TokenPunctuation, runeSize
+	}
+}
+
+func blockCommentLen(text string) int {
+	depth := 0
+	for i := 0; i < len(text)-1; i++ {
+		switch {
+		case text[i] == '/' && text[i+1] == '*':
+			depth++
+			i++
+		case text[i] == '*' && text[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(text)
+}
+
+// quotedLen return len of quoted part, started from prefixLen position. Doubled quote is escaped quote.
+func quotedLen(text string, prefixLen int, quote byte, backslashEscapes bool) int {
+	for i := prefixLen + 1; i < len(text); i++ {
+		switch {
+		case backslashEscapes && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if i+1 < len(text) && text[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+func paramLen(text string) int {
+	size := 1
+	for size < len(text) && isDigit(rune(text[size])) {
+		size++
+	}
+	if size == 1 {
+		return 0
+	}
+	return size
+}
+
+// dollarQuotedLen return len of $tag$...$tag$ string or 0 if text doesn't start with dollar quote
+func dollarQuotedLen(text string) int {
+	tagEnd := strings.IndexByte(text[1:], '$')
+	if tagEnd < 0 {
+		return 0
+	}
+	tag := text[:tagEnd+2]
+	for i, r := range tag[1 : len(tag)-1] {
+		if !isIdentifierStart(r) && (i == 0 || !isDigit(r)) {
+			return 0
+		}
+	}
+
+	bodyEnd := strings.Index(text[len(tag):], tag)
+	if bodyEnd < 0 {
+		return len(text)
+	}
+	return len(tag) + bodyEnd + len(tag)
+}
+
+func numberLen(text string) int {
+	size := 0
+	for size < len(text) && (isDigit(rune(text[size])) || text[size] == '.' || text[size] == '_') {
+		if text[size] == '.' && strings.HasPrefix(text[size:], "..") {
+			break
+		}
+		size++
+	}
+	if size < len(text) && (text[size] == 'e' || text[size] == 'E') {
+		exp := size + 1
+		if exp < len(text) && (text[exp] == '+' || text[exp] == '-') {
+			exp++
+		}
+		if exp < len(text) && isDigit(rune(text[exp])) {
+			size = exp
+			for size < len(text) && isDigit(rune(text[size])) {
+				size++
+			}
+		}
+	}
+	return size
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= utf8.RuneSelf && unicode.IsLetter(r)
+}
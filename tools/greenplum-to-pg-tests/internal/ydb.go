@@ -1,10 +1,12 @@
 package internal
 
 import (
+	"errors"
 	"log"
 	"os"
 	"strings"
 
+	"github.com/lib/pq"
 	"github.com/ydb-platform/ydb-go-genproto/protos/Ydb"
 	environ "github.com/ydb-platform/ydb-go-sdk-auth-environ"
 	"github.com/ydb-platform/ydb-go-sdk/v3"
@@ -12,12 +14,17 @@ import (
 )
 
 type YdbIssue struct {
-	Message  string                   `yaml:"message"`
-	Code     Ydb.StatusIds_StatusCode `yaml:"code"`
-	Severity uint32                   `yaml:"severity"`
+	Message  string                   `yaml:"message" json:"message"`
+	Code     Ydb.StatusIds_StatusCode `yaml:"code" json:"code,omitempty"`
+	Severity uint32                   `yaml:"severity" json:"severity,omitempty"`
 }
 
 func ExtractIssues(err error) []YdbIssue {
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) {
+		return []YdbIssue{{Message: strings.TrimSpace(pgErr.Message)}}
+	}
+
 	var issues []YdbIssue
 	ydb.IterateByIssues(err, func(message string, code Ydb.StatusIds_StatusCode, severity uint32) {
 		issues = append(issues, YdbIssue{
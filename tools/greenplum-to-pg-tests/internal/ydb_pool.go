@@ -2,25 +2,56 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ydb-platform/ydb-go-sdk/v3"
 )
 
+type YdbPoolConfig struct {
+	ProbeInterval    time.Duration // interval of health probes and reconnects, 0 disable background checks
+	ProbeTimeout     time.Duration
+	EjectAfterErrors int // eject driver after the count of consecutive transport errors
+}
+
+func DefaultYdbPoolConfig() YdbPoolConfig {
+	return YdbPoolConfig{
+		ProbeInterval:    10 * time.Second,
+		ProbeTimeout:     5 * time.Second,
+		EjectAfterErrors: 5,
+	}
+}
+
 type YdbPool struct {
-	m       sync.Mutex
-	drivers []driverInflight
+	config  YdbPoolConfig
+	options []ydb.Option
+
+	m            sync.Mutex
+	drivers      []*driverInflight
+	disconnected []string // connection strings, which failed to connect, reconnected in background
+	lastState    string
+
+	stop chan struct{}
+	wg   sync.WaitGroup
 }
 
 type driverInflight struct {
+	name     string
 	driver   *ydb.Driver
 	inflyght int
+
+	consecutiveErrors int
+	ejected           bool
 }
 
-func OpenYdbPool(ctx context.Context, connectionStrings []string, options []ydb.Option) *YdbPool {
+func OpenYdbPool(ctx context.Context, connectionStrings []string, options []ydb.Option, config YdbPoolConfig) *YdbPool {
 	var m sync.Mutex
-	drivers := make([]*ydb.Driver, 0, len(connectionStrings))
+	drivers := make([]*driverInflight, 0, len(connectionStrings))
+	var disconnected []string
 
 	var wg sync.WaitGroup
 	for i := range connectionStrings {
@@ -30,12 +61,13 @@ func OpenYdbPool(ctx context.Context, connectionStrings []string, options []ydb.
 			defer wg.Done()
 
 			driver, err := ydb.Open(ctx, cs, options...)
+			m.Lock()
+			defer m.Unlock()
 			if err == nil {
-				m.Lock()
-				drivers = append(drivers, driver)
-				m.Unlock()
+				drivers = append(drivers, &driverInflight{name: cs, driver: driver})
 			} else {
 				log.Printf("Failed connection to %q: %v", cs, err)
+				disconnected = append(disconnected, cs)
 			}
 		}()
 	}
@@ -45,34 +77,58 @@ func OpenYdbPool(ctx context.Context, connectionStrings []string, options []ydb.
 		log.Fatalf("failed to connect for all endpoints")
 	}
 
-	return NewYdbPool(drivers)
+	res := &YdbPool{
+		config:       config,
+		options:      options,
+		drivers:      drivers,
+		disconnected: disconnected,
+		stop:         make(chan struct{}),
+	}
+	res.logStateNeedLock()
+	if config.ProbeInterval > 0 {
+		res.wg.Add(1)
+		go res.healthLoop()
+	}
+	return res
 }
 
-func NewYdbPool(drivers []*ydb.Driver) *YdbPool {
+// NewYdbPool create pool of connected drivers without background health checks
+func NewYdbPool(drivers []*ydb.Driver, config YdbPoolConfig) *YdbPool {
 	if len(drivers) == 0 {
 		panic("can't create pool without drivers")
 	}
 	res := &YdbPool{
-		drivers: make([]driverInflight, len(drivers)),
+		config:  config,
+		drivers: make([]*driverInflight, len(drivers)),
+		stop:    make(chan struct{}),
 	}
 	for i, driver := range drivers {
-		res.drivers[i] = driverInflight{
-			driver:   driver,
-			inflyght: 0,
+		res.drivers[i] = &driverInflight{
+			name:   fmt.Sprintf("driver-%v", i),
+			driver: driver,
 		}
 	}
 	return res
 }
 
-// Get return driver with minimal current inflight
+// Get return healthy driver with minimal current inflight.
+// If all drivers ejected - return driver with minimal inflight from all drivers.
 func (p *YdbPool) Get() *ydb.Driver {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	minInflyght := &p.drivers[0]
-	for i := range p.drivers {
-		if p.drivers[i].inflyght < minInflyght.inflyght {
-			minInflyght = &p.drivers[i]
+	var minInflyght *driverInflight
+	for _, d := range p.drivers {
+		if !d.ejected && (minInflyght == nil || d.inflyght < minInflyght.inflyght) {
+			minInflyght = d
+		}
+	}
+	if minInflyght == nil {
+		minInflyght = p.drivers[0]
+		for _, d := range p.drivers {
+			if d.inflyght < minInflyght.inflyght {
+				minInflyght = d
+			}
 		}
 	}
 
@@ -80,19 +136,187 @@ func (p *YdbPool) Get() *ydb.Driver {
 	return minInflyght.driver
 }
 
-func (p *YdbPool) Release(driver *ydb.Driver) {
+// Release return driver to the pool, err is result of the driver usage for the circuit breaker
+func (p *YdbPool) Release(driver *ydb.Driver, err error) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	for i := range p.drivers {
-		if p.drivers[i].driver == driver {
-			p.drivers[i].inflyght--
-			if p.drivers[i].inflyght < 0 {
-				panic("the ydb driver released more times, then get")
-			}
+	d := p.findNeedLock(driver)
+	d.inflyght--
+	if d.inflyght < 0 {
+		panic("the ydb driver released more times, then get")
+	}
+	p.markResultNeedLock(d, isDriverFailure(err), err)
+}
+
+func (p *YdbPool) findNeedLock(driver *ydb.Driver) *driverInflight {
+	for _, d := range p.drivers {
+		if d.driver == driver {
+			return d
+		}
+	}
+	panic("the driver doesn't exists in the pool")
+}
+
+// markResultNeedLock count consecutive transport errors and eject driver when limit reached.
+// Ejected driver returns to the pool after successful health probe only.
+func (p *YdbPool) markResultNeedLock(d *driverInflight, failed bool, err error) {
+	if !failed {
+		if !d.ejected {
+			d.consecutiveErrors = 0
+		}
+		return
+	}
+
+	d.consecutiveErrors++
+	if !d.ejected && p.config.EjectAfterErrors > 0 && d.consecutiveErrors >= p.config.EjectAfterErrors {
+		d.ejected = true
+		log.Printf("Ydb driver %q ejected from pool after %v consecutive errors, last: %v", d.name, d.consecutiveErrors, err)
+		p.logStateNeedLock()
+	}
+}
+
+func isDriverFailure(err error) bool {
+	return err != nil && ydb.IsTransportError(err)
+}
+
+func (p *YdbPool) healthLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
 			return
+		case <-ticker.C:
+			p.probeDrivers()
+			p.reconnect()
 		}
 	}
+}
 
-	panic("the driver doesn't exists in the pool")
+// probeDrivers send simple query to every driver, successful probe return ejected driver to the pool
+func (p *YdbPool) probeDrivers() {
+	p.m.Lock()
+	drivers := append([]*driverInflight(nil), p.drivers...)
+	p.m.Unlock()
+
+	for _, d := range drivers {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.ProbeTimeout)
+		_, err := d.driver.Query().ReadRow(ctx, "SELECT 1")
+		cancel()
+
+		p.m.Lock()
+		switch {
+		case err == nil && d.ejected:
+			d.ejected = false
+			d.consecutiveErrors = 0
+			log.Printf("Ydb driver %q returned to pool after successful probe", d.name)
+			p.logStateNeedLock()
+		case err == nil:
+			d.consecutiveErrors = 0
+		case !d.ejected:
+			// any failed probe is driver failure, even if server answered with error
+			p.markResultNeedLock(d, true, fmt.Errorf("health probe failed: %w", err))
+		}
+		p.m.Unlock()
+	}
+}
+
+// reconnect try to connect to connection strings, which failed before
+func (p *YdbPool) reconnect() {
+	p.m.Lock()
+	disconnected := p.disconnected
+	p.m.Unlock()
+
+	for _, cs := range disconnected {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.ProbeTimeout)
+		driver, err := ydb.Open(ctx, cs, p.options...)
+		cancel()
+		if err != nil {
+			log.Printf("Failed reconnect to %q: %v", cs, err)
+			continue
+		}
+
+		p.m.Lock()
+		p.drivers = append(p.drivers, &driverInflight{name: cs, driver: driver})
+		p.disconnected = removeString(p.disconnected, cs)
+		log.Printf("Ydb driver %q connected", cs)
+		p.logStateNeedLock()
+		p.m.Unlock()
+	}
+}
+
+func removeString(items []string, item string) []string {
+	res := make([]string, 0, len(items))
+	for _, v := range items {
+		if v != item {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// State return short description of the pool: count of healthy drivers, ejected and disconnected endpoints
+func (p *YdbPool) State() string {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.stateNeedLock()
+}
+
+func (p *YdbPool) stateNeedLock() string {
+	var ejected []string
+	for _, d := range p.drivers {
+		if d.ejected {
+			ejected = append(ejected, d.name)
+		}
+	}
+
+	res := fmt.Sprintf("healthy drivers: %v/%v", len(p.drivers)-len(ejected), len(p.drivers)+len(p.disconnected))
+	if len(ejected) > 0 {
+		res += ", ejected: " + strings.Join(ejected, ", ")
+	}
+	if len(p.disconnected) > 0 {
+		res += ", disconnected: " + strings.Join(p.disconnected, ", ")
+	}
+	return res
+}
+
+func (p *YdbPool) logStateNeedLock() {
+	state := p.stateNeedLock()
+	if state != p.lastState {
+		log.Printf("Ydb pool %v", state)
+		p.lastState = state
+	}
+}
+
+// Inflight return current inflight queries count for every driver of the pool
+func (p *YdbPool) Inflight() []int {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	res := make([]int, len(p.drivers))
+	for i := range p.drivers {
+		res[i] = p.drivers[i].inflyght
+	}
+	return res
+}
+
+func (p *YdbPool) Close(ctx context.Context) error {
+	close(p.stop)
+	p.wg.Wait()
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	var errs []error
+	for i := range p.drivers {
+		if err := p.drivers[i].driver.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ydb-platform/ydb-go-sdk/v3"
+)
+
+func TestYdbPoolCircuitBreaker(t *testing.T) {
+	first, second := &ydb.Driver{}, &ydb.Driver{}
+	pool := NewYdbPool([]*ydb.Driver{first, second}, YdbPoolConfig{EjectAfterErrors: 2})
+	transportErr := errors.New("transport")
+	fail := func(driver *ydb.Driver) {
+		pool.m.Lock()
+		defer pool.m.Unlock()
+		pool.markResultNeedLock(pool.findNeedLock(driver), true, transportErr)
+	}
+
+	// not transport error reset counter
+	fail(first)
+	pool.Release(pool.Get(), errors.New("syntax error"))
+	fail(first)
+	require.Equal(t, "healthy drivers: 2/2", pool.State())
+
+	fail(first)
+	require.Equal(t, "healthy drivers: 1/2, ejected: driver-0", pool.State())
+
+	// ejected driver isn't used while other driver has more inflight
+	require.Same(t, second, pool.Get())
+	require.Same(t, second, pool.Get())
+	require.Equal(t, []int{0, 2}, pool.Inflight())
+	pool.Release(second, nil)
+	pool.Release(second, nil)
+
+	// success result of requests doesn't return ejected driver, only probe
+	pool.Release(pool.Get(), nil)
+	require.Equal(t, "healthy drivers: 1/2, ejected: driver-0", pool.State())
+
+	// fallback to ejected drivers, when all ejected
+	fail(second)
+	fail(second)
+	require.Equal(t, "healthy drivers: 0/2, ejected: driver-0, driver-1", pool.State())
+	require.Same(t, first, pool.Get())
+	require.Same(t, second, pool.Get())
+}